@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"activity-bot/pkg/account"
+	activities "activity-bot/pkg/activity"
+	"activity-bot/pkg/token"
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"os/signal"
+)
+
+// allowancesCmd represents the allowances command
+var allowancesCmd = &cobra.Command{
+	Use:   "allowances",
+	Short: "Lists the allowances of the accounts' tokens to known contracts, and optionally revokes them",
+	Run: func(cmd *cobra.Command, args []string) {
+		allowances()
+	},
+}
+
+var (
+	allowanceChains   []string
+	allowanceTokens   []string
+	allowanceSpenders []string
+	revoke            bool
+)
+
+func init() {
+	allowancesCmd.Flags().StringSliceVar(&allowanceChains, "chain", nil, "Chains to check, defaults to every chain of the registry")
+	allowancesCmd.Flags().StringSliceVar(&allowanceTokens, "token", nil, "Token symbols to check, defaults to the chain's token book")
+	allowancesCmd.Flags().StringSliceVar(&allowanceSpenders, "spender", nil, "Contract book names to check, defaults to the chain's contract book")
+	allowancesCmd.Flags().BoolVar(&revoke, "revoke", false, "Sets the listed allowances to zero")
+
+	rootCmd.AddCommand(allowancesCmd)
+}
+
+func allowances() {
+	// Interrupting the run cancels every in-flight call and wait
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	registry, err := loadRegistry()
+	if err != nil {
+		log.Fatal(err)
+	}
+	names := allowanceChains
+	if len(names) == 0 {
+		for _, c := range registry.Chains() {
+			names = append(names, c.Name)
+		}
+	}
+
+	am := account.NewAccountManager("./keystore")
+	am.UnlockAll("password")
+
+	chains := activities.NewChains(ctx, registry, am.NewTransactor)
+	defer chains.Close()
+
+	for _, name := range names {
+		for _, a := range am.Accounts() {
+			a := a
+			ac, err := chains.Context(ctx, name, &a)
+			if err != nil {
+				log.Printf("Skipping %s: %v\n", name, err)
+				break
+			}
+			list, err := activities.ListAllowances(ac, allowanceTokens, allowanceSpenders)
+			if err != nil {
+				log.Printf("Skipping [%s] on %s: %v\n", a.Address.Hex(), name, err)
+				continue
+			}
+			for _, allowance := range list {
+				t, err := token.Resolve(ac.CallOpts(), ac.Chain, ac.Client, allowance.Token)
+				if err != nil {
+					log.Printf("Skipping %s allowance of [%s] on %s: %v\n", allowance.Token, a.Address.Hex(), name, err)
+					continue
+				}
+				fmt.Printf("%s\t%s\t%s\t%s\t%s\n", allowance.Chain, allowance.Owner.Hex(), allowance.Token, allowance.Spender, t.Format(allowance.Amount))
+			}
+
+			if !revoke || len(list) == 0 {
+				continue
+			}
+			activity := activities.NewRevokeAllowances(name)
+			activity.Tokens = allowanceTokens
+			activity.Spenders = allowanceSpenders
+			if _, err := activities.Run(ac, activity); err != nil {
+				log.Printf("Revoking allowances of [%s] on %s failed: %v\n", a.Address.Hex(), name, err)
+				continue
+			}
+			log.Printf("[%s] revoked %d allowances on %s\n", a.Address.Hex(), len(activity.Revoked), name)
+		}
+	}
+}
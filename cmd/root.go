@@ -1,16 +1,31 @@
 package cmd
 
 import (
+	"activity-bot/pkg/chain"
 	"fmt"
 	"github.com/spf13/cobra"
 	"os"
 )
 
+var chainsConfig string
+
 var rootCmd = &cobra.Command{
 	Use:   "activity-bot",
 	Short: "Crypto Activity Bot",
 }
 
+func init() {
+	rootCmd.PersistentFlags().StringVar(&chainsConfig, "chains", "", "JSON file overriding the built-in chain configuration")
+}
+
+// loadRegistry returns the built-in chains, overridden by the chains config file when given.
+func loadRegistry() (*chain.Registry, error) {
+	if chainsConfig == "" {
+		return chain.DefaultRegistry(), nil
+	}
+	return chain.LoadRegistry(chainsConfig)
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -3,12 +3,16 @@ package cmd
 import (
 	"activity-bot/pkg/account"
 	activities "activity-bot/pkg/activity"
-	"activity-bot/pkg/random"
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/client"
+	"activity-bot/pkg/token"
+	"activity-bot/pkg/util"
 	"context"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/ethereum/go-ethereum/params"
 	"log"
+	"os"
+	"os/signal"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -22,50 +26,136 @@ var transactionCmd = &cobra.Command{
 	},
 }
 
+const localRpcUrl = "HTTP://127.0.0.1:7545"
+
+var (
+	receiptTimeout time.Duration
+	rpcUrls        []string
+	chainName      string
+	callSpecs      string
+)
+
 func init() {
+	transactionCmd.Flags().StringVar(&chainName, "chain", "", "Chain to run on, defaults to a local chain")
+	transactionCmd.Flags().StringSliceVar(&rpcUrls, "rpc", nil, "Rpc endpoints to use, the first one is the primary, defaults to the chain's endpoints")
+	transactionCmd.Flags().DurationVar(&receiptTimeout, "receipt-timeout", 0, "How long to wait for a receipt, defaults to the chain's receipt timeout")
+	transactionCmd.Flags().StringVar(&callSpecs, "call-spec", "", "JSON file of contract calls to run instead of the native transfer")
+
 	rootCmd.AddCommand(transactionCmd)
 }
 
 func run() {
-	cl, err := ethclient.Dial("HTTP://127.0.0.1:7545")
-	defer cl.Close()
+	// Interrupting the run cancels every in-flight call and wait
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	registry, err := loadRegistry()
+	if err != nil {
+		log.Fatal(err)
+	}
+	c := &chain.Chain{Name: "local", RpcUrls: []string{localRpcUrl}, BlockTime: chain.Duration(time.Second)}
+	if chainName != "" {
+		c, err = registry.Get(chainName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	urls := rpcUrls
+	if len(urls) == 0 {
+		urls = c.RpcUrls
+	}
 
+	cl, err := client.DialWithOptions(ctx, client.OptionsFor(c), urls...)
 	if err != nil {
 		panic(err)
 	}
-	chainId, err := cl.ChainID(context.Background())
+	defer cl.Close()
+	cl.StartHealthChecks(ctx, client.DefaultHealthCheckInterval)
+
+	chainId, err := cl.ChainID(ctx)
 	if err != nil {
 		panic(err)
 	}
+	if chainName == "" {
+		c.ChainId = chainId.Uint64()
+	}
+
+	waiter := util.NewWaiter(cl, time.Duration(c.BlockTime), false)
+	stopWaiter := waiter.Start(ctx)
+	defer stopWaiter()
 
 	am := account.NewAccountManager("./keystore")
 	am.UnlockAll("password")
 
-	activity := activities.NewTransferNative(
-		common.HexToAddress("0x3654114f003C108A339664f909131b4C07b0F779"),
-		random.NewSupplier(params.GWei, 10000000, 100000000))
+	activityList, err := transactionActivities(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Other chains are connected on demand, for instance by bridges checking their destination
+	chains := activities.NewChains(ctx, registry, am.NewTransactor)
+	chains.SetSigner(am.SignHash)
+	defer chains.Close()
+	if chainName != "" {
+		chains.Add(c.Name, cl, waiter)
+	}
 
 	account := am.Accounts()[0]
-	transactor, err := am.NewTransactor(account, chainId)
+	transactor, err := am.NewTransactor(account, c.EvmChainId())
 	if err != nil {
 		panic(err)
 	}
 	activityContext := activities.ActivityContext{
-		Account:    &account,
-		Client:     cl,
-		Transactor: transactor,
-		Context:    context.Background(),
+		Account:        &account,
+		Chain:          c,
+		Client:         cl,
+		Transactor:     transactor,
+		Context:        ctx,
+		Waiter:         waiter,
+		ReceiptTimeout: receiptTimeout,
+		Chains:         chains,
+		Signer:         am.SignHash,
 	}
 
-	r, err := activity.CanExecute(activityContext)
-	if err != nil {
-		log.Fatalf(err.Error())
+	for _, activity := range activityList {
+		ac, err := activityContext.OnChain(activity.Chain())
+		if err != nil {
+			log.Fatal(err)
+		}
+		r, err := activities.Run(ac, activity)
+		if err != nil {
+			log.Fatalf(err.Error())
+		}
+		log.Printf("Executed: %v", r)
 	}
-	log.Printf("Can execute: %v", r)
+}
 
-	r, err = activity.Execute(activityContext)
+// transactionActivities returns the contract calls of the call spec file when given, a native transfer on the
+// chain otherwise.
+func transactionActivities(c *chain.Chain) ([]activities.Activity, error) {
+	if callSpecs == "" {
+		valueSupplier, err := token.Native(c).Supplier("0.01", "0.1")
+		if err != nil {
+			return nil, err
+		}
+		activity := activities.NewTransferNative(
+			c.Name,
+			common.HexToAddress("0x3654114f003C108A339664f909131b4C07b0F779"),
+			valueSupplier)
+		return []activities.Activity{activity}, nil
+	}
+
+	specs, err := activities.LoadContractCallSpecs(callSpecs)
 	if err != nil {
-		log.Fatalf(err.Error())
+		return nil, err
+	}
+	activityList := make([]activities.Activity, 0, len(specs))
+	for _, spec := range specs {
+		call, err := activities.NewContractCall(spec)
+		if err != nil {
+			return nil, err
+		}
+		activityList = append(activityList, call)
 	}
-	log.Printf("Executed: %v", r)
+	return activityList, nil
 }
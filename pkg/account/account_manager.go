@@ -44,3 +44,8 @@ func (am *AccountManager) NewTransactor(account accounts.Account, chainId *big.I
 	}
 	return bind.NewKeyStoreTransactorWithChainID(am.keystore, account, chainId)
 }
+
+// SignHash signs the hash with the key of the unlocked account, for instance an EIP-712 digest.
+func (am *AccountManager) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return am.keystore.SignHash(account, hash)
+}
@@ -1,22 +1,84 @@
 package activity
 
 import (
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/client"
 	"activity-bot/pkg/util"
 	"context"
+	"fmt"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"time"
 )
 
 type ActivityContext struct {
-	Account    *accounts.Account
-	Client     *ethclient.Client
-	Transactor *bind.TransactOpts
-	Context    context.Context
-	Waiter     *util.Waiter
+	Account        *accounts.Account
+	Chain          *chain.Chain // Chain the client is connected to
+	Client         *client.Client
+	Transactor     *bind.TransactOpts
+	Context        context.Context // Bounds every RPC call and wait of the activity
+	Waiter         *util.Waiter
+	ReceiptTimeout time.Duration // Overrides the chain's default receipt timeout when set
+	Chains         *Chains       // Optional, gives access to the other chains
+	Signer         HashSigner    // Optional, signs off-chain messages such as permits
+}
+
+// HashSigner signs a hash for the account, such as AccountManager.SignHash.
+type HashSigner func(account accounts.Account, hash []byte) ([]byte, error)
+
+// OnChain returns the context of the same account on another chain, connecting to it if needed.
+func (ac ActivityContext) OnChain(name string) (ActivityContext, error) {
+	if ac.Chain != nil && ac.Chain.Name == name {
+		return ac, nil
+	}
+	if ac.Chains == nil {
+		return ActivityContext{}, fmt.Errorf("chain %s is not reachable, the activity context has no chains", name)
+	}
+	return ac.Chains.Context(ac.Context, name, ac.Account)
+}
+
+// OnLayerZeroChain returns the context of the same account on the chain with the LayerZero chain id.
+func (ac ActivityContext) OnLayerZeroChain(chainId uint16) (ActivityContext, error) {
+	if ac.Chain != nil && ac.Chain.LayerZeroChainId == chainId {
+		return ac, nil
+	}
+	if ac.Chains == nil {
+		return ActivityContext{}, fmt.Errorf("LayerZero chain %d is not reachable, the activity context has no chains", chainId)
+	}
+	c, err := ac.Chains.Registry().ByLayerZeroChainId(chainId)
+	if err != nil {
+		return ActivityContext{}, err
+	}
+	return ac.Chains.Context(ac.Context, c.Name, ac.Account)
+}
+
+// CallOpts returns the options for contract calls bound to the activity context.
+func (ac ActivityContext) CallOpts() *bind.CallOpts {
+	return &bind.CallOpts{Context: ac.Context}
+}
+
+// WaitForReceipt waits for the receipt of the transaction within the activity context.
+func (ac ActivityContext) WaitForReceipt(tx *types.Transaction) (*types.Receipt, error) {
+	timeout := ac.ReceiptTimeout
+	if timeout == 0 && ac.Chain != nil {
+		timeout = time.Duration(ac.Chain.ReceiptTimeout)
+	}
+	return util.WaitForReceipt(ac.Context, tx, ac.Waiter, timeout)
 }
 
 type Activity interface {
+	// Chain returns the name of the chain the activity runs on.
+	Chain() string
 	CanExecute(activityContext ActivityContext) (bool, error)
 	Execute(activityContext ActivityContext) (bool, error)
 }
+
+// ContractActivity is implemented by activities interacting with contracts, their code is checked
+// to be deployed before anything is signed.
+type ContractActivity interface {
+	Activity
+	// Contracts returns the addresses of the contracts the activity uses on the chain.
+	Contracts(c *chain.Chain) ([]common.Address, error)
+}
@@ -0,0 +1,122 @@
+package activity
+
+import (
+	"activity-bot/pkg/abi/erc20"
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/client"
+	"activity-bot/pkg/client/clienttest"
+	"activity-bot/pkg/util"
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// dialFakeNode returns a client of the fake node and a polling waiter on it, both stopped when the test ends.
+func dialFakeNode(t *testing.T, eth interface{}) (*client.Client, *util.Waiter) {
+	cl := clienttest.Dial(t, clienttest.Serve(t, eth))
+	waiter := util.NewWaiter(cl, 0, false)
+	t.Cleanup(waiter.Start(context.Background()))
+	return cl, waiter
+}
+
+// newFakeNodeContext returns the context of a fresh account on Avalanche, connected to the fake node.
+func newFakeNodeContext(t *testing.T, chainId int64, eth interface{}) ActivityContext {
+	cl, waiter := dialFakeNode(t, eth)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactor, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(chainId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	avalanche, err := chain.DefaultRegistry().Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ActivityContext{
+		Account:    &accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)},
+		Chain:      avalanche,
+		Client:     cl,
+		Transactor: transactor,
+		Context:    context.Background(),
+		Waiter:     waiter,
+	}
+}
+
+func TestWaitForReceiptStops(t *testing.T) {
+	tx := types.NewTx(&types.LegacyTx{Nonce: 1, Gas: 21000, GasPrice: big.NewInt(1)})
+	tests := []struct {
+		name           string
+		mined          bool
+		cancel         bool
+		receiptTimeout time.Duration
+		wantErr        error
+	}{
+		{name: "mined", mined: true},
+		{name: "cancelled context", cancel: true, wantErr: context.Canceled},
+		{name: "chain receipt timeout", receiptTimeout: 200 * time.Millisecond, wantErr: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			avalanche, err := chain.DefaultRegistry().Get(chain.Avalanche)
+			if err != nil {
+				t.Fatal(err)
+			}
+			node := newFakeErc20Node(avalanche)
+			if tt.mined {
+				node.receipts[tx.Hash()] = &types.Receipt{Status: types.ReceiptStatusSuccessful, TxHash: tx.Hash(), BlockNumber: big.NewInt(1), Logs: []*types.Log{}}
+			}
+			ac := newAllowanceContext(t, node)
+			c := *ac.Chain
+			c.ReceiptTimeout = chain.Duration(tt.receiptTimeout)
+			ac.Chain = &c
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ac.Context = ctx
+			if tt.cancel {
+				time.AfterFunc(200*time.Millisecond, cancel)
+			}
+
+			started := time.Now()
+			receipt, err := ac.WaitForReceipt(tx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WaitForReceipt() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && receipt.TxHash != tx.Hash() {
+				t.Errorf("WaitForReceipt() = receipt of %s, want %s", receipt.TxHash.Hex(), tx.Hash().Hex())
+			}
+			if elapsed := time.Since(started); elapsed > 5*time.Second {
+				t.Errorf("WaitForReceipt() returned after %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestCallOptsFollowTheContext(t *testing.T) {
+	avalanche, err := chain.DefaultRegistry().Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ac := newAllowanceContext(t, newFakeErc20Node(avalanche))
+	token, err := erc20.NewErc20(avalanche.Tokens["USDC"], ac.Client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := token.Allowance(ac.CallOpts(), ac.Account.Address, avalanche.Contracts[chain.StargateRouter]); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ac.Context = ctx
+	if _, err := token.Allowance(ac.CallOpts(), ac.Account.Address, avalanche.Contracts[chain.StargateRouter]); !errors.Is(err, context.Canceled) {
+		t.Errorf("Allowance() with a cancelled context error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,45 @@
+package activity
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+)
+
+// approvable is implemented by the ERC-20 bindings.
+type approvable interface {
+	Allowance(opts *bind.CallOpts, owner common.Address, spender common.Address) (*big.Int, error)
+	Approve(opts *bind.TransactOpts, spender common.Address, amount *big.Int) (*types.Transaction, error)
+}
+
+// ensureAllowance approves the spender for the amount if the account allowance is lower, and waits
+// for the approval to be mined.
+func ensureAllowance(ac ActivityContext, token approvable, spender common.Address, amount *big.Int) error {
+	allowance, err := token.Allowance(ac.CallOpts(), ac.Account.Address, spender)
+	if err != nil {
+		return err
+	}
+	if allowance.Cmp(amount) >= 0 {
+		return nil
+	}
+
+	log.Printf("[%s] approving %s for %s\n", ac.Account.Address.Hex(), spender.Hex(), amount.String())
+	ac.Transactor.Value = big.NewInt(0)
+	tx, err := token.Approve(ac.Transactor, spender, amount)
+	if err != nil {
+		return err
+	}
+	log.Printf("Approve tx sent: %s", tx.Hash().Hex())
+	receipt, err := ac.WaitForReceipt(tx)
+	if err != nil {
+		return err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return errors.New(fmt.Sprintf("Approve tx failed: %s", receipt.TxHash.Hex()))
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package activity
+
+import (
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/layerzero"
+	"activity-bot/pkg/token"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+	"time"
+)
+
+// DefaultOFTDstGas is the gas of the receive call of OFT transfers on the destination chain.
+const DefaultOFTDstGas = 250000
+
+// BridgeLeg records one leg of a BTC.b bridge.
+type BridgeLeg struct {
+	FromChainId uint16 // LayerZero chain id of the source chain
+	ToChainId   uint16 // LayerZero chain id of the destination chain
+	Amount      *big.Int
+	SourceTx    common.Hash
+	Delivery    *Delivery // Nil when the delivery is not tracked
+}
+
+// bitcoinOFT is implemented by the adapters of the BTC.b OFT bindings, which only differ by the package of the
+// sendFrom call parameters.
+type bitcoinOFT interface {
+	QuoteOFTFee(opts *bind.CallOpts, dstChainId uint16, amount *big.Int) (*big.Int, error)
+	EstimateSendFee(opts *bind.CallOpts, dstChainId uint16, toAddress [32]byte, amount *big.Int, useZro bool, adapterParams []byte) (struct {
+		NativeFee *big.Int
+		ZroFee    *big.Int
+	}, error)
+	// Send calls sendFrom with the refund address set to the sender and no ZRO payment.
+	Send(opts *bind.TransactOpts, from common.Address, dstChainId uint16, toAddress [32]byte, amount *big.Int, minAmount *big.Int, adapterParams []byte) (*types.Transaction, error)
+}
+
+// bitcoinBridge is the part of the BTC.b bridges shared by every source chain.
+type bitcoinBridge struct {
+	ToChainIds      []uint16      // LayerZero chain ids of the possible destinations
+	MinAmount       string        // Decimal amount of BTC.b, such as "0.0005"
+	MaxAmount       string        // Decimal amount of BTC.b
+	DstGas          uint64        // Gas of the receive call on the destination chain
+	DeliveryTimeout time.Duration // Optional, waits for the funds on the destination chain when set
+	Return          bool          // Bridges the funds back once delivered, needs a delivery timeout
+	Delivery        *Delivery     // Set on execute once the delivery is confirmed
+	Legs            []BridgeLeg   // Set on execute, the outbound leg then the return legs
+	name            string
+	oftAddress      common.Address // Computed on can execute
+	btcb            *token.Token   // Computed on can execute
+	toChainId       uint16         // Computed on can execute
+	value           *big.Int       // Computed on can execute
+}
+
+// drawBridgeValue draws the value to bridge between the decimal amounts, not above the BTC.b balance.
+func (b *bitcoinBridge) drawBridgeValue(ac ActivityContext, balance *big.Int) error {
+	valueSupplier, err := b.btcb.Supplier(b.MinAmount, b.MaxAmount)
+	if err != nil {
+		return err
+	}
+	log.Printf("Generating a random value to bridge between %s and %s BTC.b\n", b.MinAmount, b.MaxAmount)
+	if balance.Cmp(valueSupplier.Min()) < 0 {
+		return errors.New(fmt.Sprintf("Account [%s] has not enough BTC.b balance to execute bridge", ac.Account.Address.Hex()))
+	}
+	b.value, err = drawValue(ac, valueSupplier, balance)
+	return err
+}
+
+// execute bridges the value through the OFT to the destination, then tracks the delivery and bridges back when
+// configured. The token is approved to the OFT first unless it is nil, when the OFT is the token itself.
+func (b *bitcoinBridge) execute(ac ActivityContext, token approvable, oft bitcoinOFT) (bool, error) {
+	ac.Transactor.Context = ac.Context
+	log.Printf("[%s] started cross swapping %s BTC.b to LayerZero chain %d using %s\n", ac.Account.Address.Hex(), b.btcb.Format(b.value), b.toChainId, b.name)
+	b.Delivery = nil
+	b.Legs = nil
+
+	if token != nil {
+		log.Println("Checking if BTC.b allowance required")
+		if err := ensureAllowance(ac, token, b.oftAddress, b.value); err != nil {
+			return false, err
+		}
+	}
+
+	var addressArr [32]byte
+	copy(addressArr[12:], ac.Account.Address.Bytes())
+	params, err := layerzero.NewAdapterParams(b.DstGas).Encode()
+	if err != nil {
+		return false, err
+	}
+
+	// Quote OFT and LZ Fees
+	oftFee, err := oft.QuoteOFTFee(ac.CallOpts(), b.toChainId, b.value)
+	if err != nil {
+		return false, err
+	}
+	fees, err := oft.EstimateSendFee(ac.CallOpts(), b.toChainId, addressArr, b.value, false, params)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("[%s] OFT fee: %v, LZ Quote Fees: %v\n", ac.Account.Address, oftFee, fees.NativeFee)
+	minAmount := new(big.Int).Sub(b.value, oftFee)
+
+	var tracker *DeliveryTracker
+	var destinationFromBlock *big.Int
+	if b.DeliveryTimeout > 0 {
+		destination, err := ac.OnLayerZeroChain(b.toChainId)
+		if err != nil {
+			return false, err
+		}
+		destinationBridge, err := destination.Chain.Contract(chain.BitcoinBridge)
+		if err != nil {
+			return false, err
+		}
+		tracker = NewDeliveryTracker(destination.Waiter, destinationBridge, b.DeliveryTimeout)
+		destinationFromBlock, err = tracker.Checkpoint(ac.Context)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// Bridge
+	ac.Transactor.Value = fees.NativeFee
+	ac.Transactor.GasLimit = 300000
+	sentAt := time.Now()
+	tx, err := oft.Send(ac.Transactor, ac.Account.Address, b.toChainId, addressArr, b.value, minAmount, params)
+	ac.Transactor.Value = big.NewInt(0)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("%s sendFrom tx sent: %s", b.name, tx.Hash().Hex())
+	receipt, err := ac.WaitForReceipt(tx)
+	if err != nil {
+		return false, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return false, errors.New(fmt.Sprintf("%s sendFrom tx failed: %s", b.name, receipt.TxHash.Hex()))
+	}
+	b.Legs = append(b.Legs, BridgeLeg{
+		FromChainId: ac.Chain.LayerZeroChainId,
+		ToChainId:   b.toChainId,
+		Amount:      b.value,
+		SourceTx:    tx.Hash(),
+	})
+
+	if tracker != nil {
+		topics := [][]common.Hash{
+			{receiveFromChainEventId},
+			{common.BigToHash(big.NewInt(int64(ac.Chain.LayerZeroChainId)))},
+			{common.BytesToHash(ac.Account.Address.Bytes())},
+		}
+		delivery, err := tracker.AwaitEvent(ac.Context, destinationFromBlock, tx.Hash(), sentAt, topics)
+		if err != nil {
+			return false, err
+		}
+		b.Delivery = delivery
+		b.Legs[0].Delivery = delivery
+	}
+
+	if b.Return {
+		legs, err := bridgeBack(ac, b.toChainId, b.btcb.Format(minAmount), b.DstGas, b.DeliveryTimeout)
+		if err != nil {
+			return false, err
+		}
+		b.Legs = append(b.Legs, legs...)
+	}
+	return true, nil
+}
+
+// trustedRemoteLookup is implemented by the BTC.b OFT bindings, it returns the remote and local addresses
+// of the trusted path to the chain, empty when there is none.
+type trustedRemoteLookup func(opts *bind.CallOpts, chainId uint16) ([]byte, error)
+
+// trustedDestinations returns the destinations the OFT trusts. When the registry knows the BTC.b bridge of a
+// destination, the trusted remote must be that contract.
+func trustedDestinations(ac ActivityContext, lookup trustedRemoteLookup, toChainIds []uint16) ([]uint16, error) {
+	trusted := make([]uint16, 0, len(toChainIds))
+	for _, toChainId := range toChainIds {
+		path, err := lookup(ac.CallOpts(), toChainId)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) < common.AddressLength {
+			log.Printf("BTC.b bridge has no trusted remote on LayerZero chain %d\n", toChainId)
+			continue
+		}
+		remote := common.BytesToAddress(path[:common.AddressLength])
+		if ac.Chains != nil {
+			if destination, err := ac.Chains.Registry().ByLayerZeroChainId(toChainId); err == nil {
+				if expected, err := destination.Contract(chain.BitcoinBridge); err == nil && expected != remote {
+					log.Printf("BTC.b bridge trusts %s on %s, expected %s\n", remote.Hex(), destination.Name, expected.Hex())
+					continue
+				}
+			}
+		}
+		trusted = append(trusted, toChainId)
+	}
+	if len(trusted) == 0 {
+		return nil, errors.New(fmt.Sprintf("None of the LayerZero chains %v is a trusted remote of the BTC.b bridge", toChainIds))
+	}
+	return trusted, nil
+}
+
+// bridgeBack bridges the decimal amount of BTC.b from the destination chain back to the chain of the activity
+// context, and returns the legs of the return.
+func bridgeBack(ac ActivityContext, toChainId uint16, amount string, dstGas uint64, deliveryTimeout time.Duration) ([]BridgeLeg, error) {
+	destination, err := ac.OnLayerZeroChain(toChainId)
+	if err != nil {
+		return nil, err
+	}
+	returnTo := []uint16{ac.Chain.LayerZeroChainId}
+	log.Printf("[%s] bridging %s BTC.b back from %s to %s\n", ac.Account.Address.Hex(), amount, destination.Chain.Name, ac.Chain.Name)
+
+	if destination.Chain.Name == chain.Avalanche {
+		back := NewBitcoinBridgeAvax(returnTo, amount, amount)
+		back.DstGas = dstGas
+		back.DeliveryTimeout = deliveryTimeout
+		if err := runReturnLeg(destination, back); err != nil {
+			return nil, err
+		}
+		return back.Legs, nil
+	}
+	back := NewBitcoinBridgePolygon(returnTo, amount, amount)
+	back.FromChain = destination.Chain.Name
+	back.DstGas = dstGas
+	back.DeliveryTimeout = deliveryTimeout
+	if err := runReturnLeg(destination, back); err != nil {
+		return nil, err
+	}
+	return back.Legs, nil
+}
+
+func runReturnLeg(ac ActivityContext, activity Activity) error {
+	executed, err := Run(ac, activity)
+	if err != nil {
+		return fmt.Errorf("return leg on %s failed: %w", ac.Chain.Name, err)
+	}
+	if !executed {
+		return fmt.Errorf("return leg on %s can not be executed", ac.Chain.Name)
+	}
+	return nil
+}
@@ -3,11 +3,10 @@ package activity
 import (
 	"activity-bot/pkg/abi/bitcoinBridgeAvax"
 	"activity-bot/pkg/abi/wrappedBitcoinAvax"
-	"activity-bot/pkg/constants"
+	"activity-bot/pkg/chain"
 	"activity-bot/pkg/random"
-	"activity-bot/pkg/util"
+	"activity-bot/pkg/token"
 	"errors"
-	"fmt"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -15,121 +14,94 @@ import (
 	"math/big"
 )
 
+// BitcoinBridgeAvax bridges BTC.b from Avalanche through the BTC.b proxy OFT to one of the destination chains.
 type BitcoinBridgeAvax struct {
-	FromChainId        uint16
-	ToChainId          uint16
+	bitcoinBridge
 	bitcoinBridgeAvax  *bitcoinBridgeAvax.BitcoinBridgeAvax
 	wrappedBitcoinAvax *wrappedBitcoinAvax.WrappedBitcoinAvax
-	ValueSupplier      *random.Supplier
-	value              *big.Int // Computed on can execute
 }
 
-func NewBitcoinBridgeAvax(fromChainId uint16, toChainId uint16, valueSupplier *random.Supplier) *BitcoinBridgeAvax {
+func NewBitcoinBridgeAvax(toChainIds []uint16, minAmount string, maxAmount string) *BitcoinBridgeAvax {
 	return &BitcoinBridgeAvax{
-		FromChainId:   fromChainId,
-		ToChainId:     toChainId,
-		ValueSupplier: valueSupplier,
+		bitcoinBridge: bitcoinBridge{
+			ToChainIds: toChainIds,
+			MinAmount:  minAmount,
+			MaxAmount:  maxAmount,
+			DstGas:     DefaultOFTDstGas,
+			name:       "BitcoinBridgeAvax",
+		},
 	}
 }
 
+func (b *BitcoinBridgeAvax) Chain() string {
+	return chain.Avalanche
+}
+
+func (b *BitcoinBridgeAvax) Contracts(c *chain.Chain) ([]common.Address, error) {
+	return addressBook(c, []string{chain.BitcoinBridge}, []string{"BTC.b"})
+}
+
 func (b *BitcoinBridgeAvax) CanExecute(ac ActivityContext) (bool, error) {
-	log.Printf("Creating BitcoinBridgeAvax contract instance\n")
-	bitcoinBridgeContract, err := bitcoinBridgeAvax.NewBitcoinBridgeAvax(common.HexToAddress(constants.AVA_BITCOIN_BRIDGE_CONTRACT), ac.Client)
+	if len(b.ToChainIds) == 0 {
+		return false, errors.New("BitcoinBridgeAvax has no destination chain")
+	}
+	if b.Return && b.DeliveryTimeout == 0 {
+		return false, errors.New("BitcoinBridgeAvax needs a delivery timeout to bridge back")
+	}
+	bridgeAddress, err := ac.Chain.Contract(chain.BitcoinBridge)
 	if err != nil {
 		return false, err
 	}
-	b.bitcoinBridgeAvax = bitcoinBridgeContract
-
-	log.Printf("Creating Btc.B contract instance\n")
-	wrappedBitcoinContract, err := wrappedBitcoinAvax.NewWrappedBitcoinAvax(common.HexToAddress(constants.AVA_BTCB), ac.Client)
+	b.btcb, err = token.Resolve(ac.CallOpts(), ac.Chain, ac.Client, "BTC.b")
 	if err != nil {
 		return false, err
 	}
-	b.wrappedBitcoinAvax = wrappedBitcoinContract
 
-	log.Printf("Generating a random value to bridge using value supplier [%s, %s]\n", b.ValueSupplier.Min().String(), b.ValueSupplier.Max().String())
-	balance, err := b.wrappedBitcoinAvax.BalanceOf(&bind.CallOpts{}, ac.Account.Address)
+	log.Printf("Creating BitcoinBridgeAvax contract instance\n")
+	bitcoinBridgeContract, err := bitcoinBridgeAvax.NewBitcoinBridgeAvax(bridgeAddress, ac.Client)
 	if err != nil {
 		return false, err
 	}
-	if balance.Cmp(b.ValueSupplier.Min()) < 0 {
-		return false, errors.New(fmt.Sprintf("Account [%s] has not enough AVA balance to execute transfer", ac.Account.Address.Hex()))
-	}
-
-	b.value = b.ValueSupplier.Supply()
-	// Make sure our value is not bigger than the account balance
-	for {
-		if b.value.Cmp(balance) > 0 {
-			b.value = b.ValueSupplier.Supply()
-		} else {
-			break
-		}
-	}
-
-	return true, nil
-}
-
-func (b *BitcoinBridgeAvax) Execute(ac ActivityContext) (bool, error) {
-	log.Printf("[%s] started cross swapping using BitcoinBridgeAvax\n", ac.Account.Address.Hex())
+	b.bitcoinBridgeAvax = bitcoinBridgeContract
+	b.oftAddress = bridgeAddress
 
-	log.Println("Checking if Btc.B allowance required")
-	allowance, err := b.wrappedBitcoinAvax.Allowance(&bind.CallOpts{}, common.HexToAddress(constants.AVA_BITCOIN_BRIDGE_CONTRACT), ac.Account.Address)
+	log.Printf("Creating Btc.B contract instance\n")
+	wrappedBitcoinContract, err := wrappedBitcoinAvax.NewWrappedBitcoinAvax(b.btcb.Address, ac.Client)
 	if err != nil {
 		return false, err
 	}
-	if allowance.Cmp(b.value) < 0 {
-		ac.Transactor.Value = big.NewInt(0)
-		tx, err := b.wrappedBitcoinAvax.Approve(ac.Transactor, common.HexToAddress(constants.AVA_BITCOIN_BRIDGE_CONTRACT), big.NewInt(0).Sub(b.value, allowance))
-		if err != nil {
-			return false, err
-		}
-		log.Printf("Approve tx sent: %s", tx.Hash().Hex())
-		receipt, err := util.WaitForReceipt(tx, ac.Waiter)
-		if err != nil {
-			return false, err
-		}
-		if receipt.Status != types.ReceiptStatusSuccessful {
-			return false, errors.New(fmt.Sprintf("Approve tx failed: %s", receipt.TxHash.Hex()))
-		}
-	}
+	b.wrappedBitcoinAvax = wrappedBitcoinContract
 
-	// Quote LZ Fees
-	fees, err := b.bitcoinBridgeAvax.QuoteOFTFee(&bind.CallOpts{}, b.ToChainId, b.value)
+	destinations, err := trustedDestinations(ac, b.bitcoinBridgeAvax.TrustedRemoteLookup, b.ToChainIds)
 	if err != nil {
 		return false, err
 	}
-	log.Printf("[%s] OFT Quote Fees: %v\n", ac.Account.Address, fees)
 
-	// Bridge
-	var addressBytes []byte
-	addressBytes = append(make([]byte, 12), ac.Account.Address.Bytes()...)
-	var addressArr [32]byte
-	copy(addressArr[:], addressBytes)
-	staticParams := common.Hex2Bytes("0002000000000000000000000000000000000000000000000000000000000003d0900000000000000000000000000000000000000000000000000000000000000000")
-	params := append(staticParams, ac.Account.Address.Bytes()...)
-	ac.Transactor.Value = fees
-	ac.Transactor.GasLimit = 300000
-	tx, err := b.bitcoinBridgeAvax.SendFrom(
-		ac.Transactor,
-		ac.Account.Address,
-		b.FromChainId,
-		addressArr,
-		b.value,
-		b.value,
-		bitcoinBridgeAvax.ICommonOFTLzCallParams{
-			RefundAddress:     ac.Account.Address,
-			ZroPaymentAddress: common.HexToAddress("0x"),
-			AdapterParams:     params,
-		})
+	balance, err := b.wrappedBitcoinAvax.BalanceOf(ac.CallOpts(), ac.Account.Address)
 	if err != nil {
 		return false, err
 	}
-	receipt, err := util.WaitForReceipt(tx, ac.Waiter)
-	if err != nil {
+	if err := b.drawBridgeValue(ac, balance); err != nil {
 		return false, err
 	}
-	if receipt.Status != types.ReceiptStatusSuccessful {
-		return false, errors.New(fmt.Sprintf("Approve tx failed: %s", receipt.TxHash.Hex()))
-	}
+	b.toChainId = random.Pick(destinations)
+
 	return true, nil
 }
+
+func (b *BitcoinBridgeAvax) Execute(ac ActivityContext) (bool, error) {
+	return b.execute(ac, b.wrappedBitcoinAvax, bitcoinBridgeAvaxOFT{b.bitcoinBridgeAvax})
+}
+
+// bitcoinBridgeAvaxOFT adapts the BTC.b proxy OFT binding to bitcoinOFT.
+type bitcoinBridgeAvaxOFT struct {
+	*bitcoinBridgeAvax.BitcoinBridgeAvax
+}
+
+func (o bitcoinBridgeAvaxOFT) Send(opts *bind.TransactOpts, from common.Address, dstChainId uint16, toAddress [32]byte, amount *big.Int, minAmount *big.Int, adapterParams []byte) (*types.Transaction, error) {
+	return o.SendFrom(opts, from, dstChainId, toAddress, amount, minAmount, bitcoinBridgeAvax.ICommonOFTLzCallParams{
+		RefundAddress:     from,
+		ZroPaymentAddress: common.Address{},
+		AdapterParams:     adapterParams,
+	})
+}
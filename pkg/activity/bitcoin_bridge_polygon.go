@@ -0,0 +1,105 @@
+package activity
+
+import (
+	"activity-bot/pkg/abi/bitcoinBridgePolygon"
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/random"
+	"activity-bot/pkg/token"
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+)
+
+// BitcoinBridgePolygon bridges BTC.b from Polygon through the BTC.b OFT to one of the destination chains.
+// Outside Avalanche the OFT is the BTC.b token itself, so it also bridges from the other chains with a BTC.b
+// bridge in the registry.
+type BitcoinBridgePolygon struct {
+	bitcoinBridge
+	FromChain            string // Defaults to Polygon
+	bitcoinBridgePolygon *bitcoinBridgePolygon.BitcoinBridgePolygon
+}
+
+func NewBitcoinBridgePolygon(toChainIds []uint16, minAmount string, maxAmount string) *BitcoinBridgePolygon {
+	return &BitcoinBridgePolygon{
+		bitcoinBridge: bitcoinBridge{
+			ToChainIds: toChainIds,
+			MinAmount:  minAmount,
+			MaxAmount:  maxAmount,
+			DstGas:     DefaultOFTDstGas,
+			name:       "BitcoinBridgePolygon",
+		},
+		FromChain: chain.Polygon,
+	}
+}
+
+func (b *BitcoinBridgePolygon) Chain() string {
+	return b.FromChain
+}
+
+func (b *BitcoinBridgePolygon) Contracts(c *chain.Chain) ([]common.Address, error) {
+	return addressBook(c, []string{chain.BitcoinBridge}, nil)
+}
+
+func (b *BitcoinBridgePolygon) CanExecute(ac ActivityContext) (bool, error) {
+	if len(b.ToChainIds) == 0 {
+		return false, errors.New("BitcoinBridgePolygon has no destination chain")
+	}
+	if b.Return && b.DeliveryTimeout == 0 {
+		return false, errors.New("BitcoinBridgePolygon needs a delivery timeout to bridge back")
+	}
+	bridgeAddress, err := ac.Chain.Contract(chain.BitcoinBridge)
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("Creating BitcoinBridgePolygon contract instance\n")
+	bitcoinBridgeContract, err := bitcoinBridgePolygon.NewBitcoinBridgePolygon(bridgeAddress, ac.Client)
+	if err != nil {
+		return false, err
+	}
+	b.bitcoinBridgePolygon = bitcoinBridgeContract
+	b.oftAddress = bridgeAddress
+	// The OFT is the BTC.b token, whether or not the chain's token book lists it
+	decimals, err := b.bitcoinBridgePolygon.Decimals(ac.CallOpts())
+	if err != nil {
+		return false, err
+	}
+	b.btcb = &token.Token{Chain: ac.Chain.Name, Address: bridgeAddress, Symbol: "BTC.b", Decimals: decimals}
+
+	destinations, err := trustedDestinations(ac, b.bitcoinBridgePolygon.TrustedRemoteLookup, b.ToChainIds)
+	if err != nil {
+		return false, err
+	}
+
+	balance, err := b.bitcoinBridgePolygon.BalanceOf(ac.CallOpts(), ac.Account.Address)
+	if err != nil {
+		return false, err
+	}
+	if err := b.drawBridgeValue(ac, balance); err != nil {
+		return false, err
+	}
+	b.toChainId = random.Pick(destinations)
+
+	return true, nil
+}
+
+// Execute bridges without approval, the OFT is the BTC.b token and burns the account's tokens itself.
+func (b *BitcoinBridgePolygon) Execute(ac ActivityContext) (bool, error) {
+	return b.execute(ac, nil, bitcoinBridgePolygonOFT{b.bitcoinBridgePolygon})
+}
+
+// bitcoinBridgePolygonOFT adapts the BTC.b OFT binding to bitcoinOFT.
+type bitcoinBridgePolygonOFT struct {
+	*bitcoinBridgePolygon.BitcoinBridgePolygon
+}
+
+func (o bitcoinBridgePolygonOFT) Send(opts *bind.TransactOpts, from common.Address, dstChainId uint16, toAddress [32]byte, amount *big.Int, minAmount *big.Int, adapterParams []byte) (*types.Transaction, error) {
+	return o.SendFrom(opts, from, dstChainId, toAddress, amount, minAmount, bitcoinBridgePolygon.ICommonOFTLzCallParams{
+		RefundAddress:     from,
+		ZroPaymentAddress: common.Address{},
+		AdapterParams:     adapterParams,
+	})
+}
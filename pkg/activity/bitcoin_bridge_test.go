@@ -0,0 +1,46 @@
+package activity
+
+import (
+	"activity-bot/pkg/chain"
+	"context"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"reflect"
+	"testing"
+)
+
+func TestTrustedDestinations(t *testing.T) {
+	registry := chain.DefaultRegistry()
+	polygon, err := registry.Get(chain.Polygon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := common.HexToAddress("0x2297aEbD383787A160DD0d9F71508148769342E3")
+	remotes := map[uint16][]byte{
+		// Trusted path to the registry bridge
+		polygon.LayerZeroChainId: append(polygon.Contracts[chain.BitcoinBridge].Bytes(), local.Bytes()...),
+		// Trusted path to a chain the registry does not know
+		165: append(common.HexToAddress("0x1111111111111111111111111111111111111111").Bytes(), local.Bytes()...),
+		// Trusted path to another contract than the registry bridge
+		106: append(common.HexToAddress("0x2222222222222222222222222222222222222222").Bytes(), local.Bytes()...),
+	}
+	lookup := func(opts *bind.CallOpts, chainId uint16) ([]byte, error) {
+		return remotes[chainId], nil
+	}
+	ac := ActivityContext{
+		Context: context.Background(),
+		Chains:  NewChains(context.Background(), registry, nil),
+	}
+
+	got, err := trustedDestinations(ac, lookup, []uint16{polygon.LayerZeroChainId, 165, 106, 110})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint16{polygon.LayerZeroChainId, 165}; !reflect.DeepEqual(got, want) {
+		t.Errorf("trustedDestinations() = %v, want %v", got, want)
+	}
+
+	if _, err := trustedDestinations(ac, lookup, []uint16{106, 110}); err == nil {
+		t.Error("trustedDestinations() error = nil, want an error without trusted destination")
+	}
+}
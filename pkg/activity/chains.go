@@ -0,0 +1,183 @@
+package activity
+
+import (
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/client"
+	"activity-bot/pkg/util"
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// TransactorFactory creates a transactor signing for the account on the chain, such as AccountManager.NewTransactor.
+type TransactorFactory func(account accounts.Account, chainId *big.Int) (*bind.TransactOpts, error)
+
+// connection is the client and waiter of a chain, set once dialed is closed unless dialing failed.
+type connection struct {
+	client *client.Client
+	waiter *util.Waiter
+	dialed chan struct{}
+	err    error
+}
+
+// Chains gives activities access to every chain of the registry. Clients and waiters are created on first
+// use and shared by all accounts, transactors are created on first use for each account and chain.
+type Chains struct {
+	ctx           context.Context // Lifetime of the connections
+	registry      *chain.Registry
+	newTransactor TransactorFactory
+	signer        HashSigner // Optional, set on the contexts
+	connections   map[string]*connection
+	transactors   map[string]map[common.Address]*bind.TransactOpts
+	clients       []*client.Client // Clients dialed by the chains, closed on Close
+	stopWaiters   []context.CancelFunc
+	lock          *sync.Mutex
+}
+
+// NewChains returns chains connected lazily, the connections live until the context is done or Close is called.
+func NewChains(ctx context.Context, registry *chain.Registry, newTransactor TransactorFactory) *Chains {
+	return &Chains{
+		ctx:           ctx,
+		registry:      registry,
+		newTransactor: newTransactor,
+		connections:   make(map[string]*connection),
+		transactors:   make(map[string]map[common.Address]*bind.TransactOpts),
+		clients:       make([]*client.Client, 0),
+		stopWaiters:   make([]context.CancelFunc, 0),
+		lock:          &sync.Mutex{},
+	}
+}
+
+// Add registers an existing client and waiter for the chain, for instance the ones the runner already dialed.
+func (c *Chains) Add(name string, client *client.Client, waiter *util.Waiter) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	dialed := make(chan struct{})
+	close(dialed)
+	c.connections[name] = &connection{client: client, waiter: waiter, dialed: dialed}
+}
+
+// SetSigner sets the signer of the contexts returned by the chains.
+func (c *Chains) SetSigner(signer HashSigner) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.signer = signer
+}
+
+func (c *Chains) Registry() *chain.Registry {
+	return c.registry
+}
+
+// Context returns an activity context for the account on the chain, connecting to it if needed.
+func (c *Chains) Context(ctx context.Context, name string, account *accounts.Account) (ActivityContext, error) {
+	ch, err := c.registry.Get(name)
+	if err != nil {
+		return ActivityContext{}, err
+	}
+	conn, err := c.connection(ctx, ch)
+	if err != nil {
+		return ActivityContext{}, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.transactors[name]; !ok {
+		c.transactors[name] = make(map[common.Address]*bind.TransactOpts)
+	}
+	transactor, ok := c.transactors[name][account.Address]
+	if !ok {
+		transactor, err = c.newTransactor(*account, ch.EvmChainId())
+		if err != nil {
+			return ActivityContext{}, fmt.Errorf("unable to create transactor on chain %s: %w", name, err)
+		}
+		c.transactors[name][account.Address] = transactor
+	}
+
+	return ActivityContext{
+		Account:    account,
+		Chain:      ch,
+		Client:     conn.client,
+		Transactor: transactor,
+		Context:    ctx,
+		Waiter:     conn.waiter,
+		Chains:     c,
+		Signer:     c.signer,
+	}, nil
+}
+
+// connection returns the connection to the chain. The first caller dials it without holding the lock, so the
+// chains already connected stay usable meanwhile, concurrent callers wait for the same dial.
+func (c *Chains) connection(ctx context.Context, ch *chain.Chain) (*connection, error) {
+	c.lock.Lock()
+	conn, ok := c.connections[ch.Name]
+	if !ok {
+		conn = &connection{dialed: make(chan struct{})}
+		c.connections[ch.Name] = conn
+	}
+	c.lock.Unlock()
+	if !ok {
+		c.connect(ctx, ch, conn)
+	}
+
+	select {
+	case <-conn.dialed:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if conn.err != nil {
+		return nil, conn.err
+	}
+	return conn, nil
+}
+
+// connect dials the chain for the pending connection. A failed dial is forgotten so the next caller dials again.
+func (c *Chains) connect(ctx context.Context, ch *chain.Chain, conn *connection) {
+	defer close(conn.dialed)
+	dialCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	// Websocket endpoints come last, they serve the subscriptions of the waiter
+	urls := append(append(make([]string, 0, len(ch.RpcUrls)+len(ch.WsUrls)), ch.RpcUrls...), ch.WsUrls...)
+	cl, err := client.DialWithOptions(dialCtx, client.OptionsFor(ch), urls...)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.connections[ch.Name] != conn {
+		// Closed while dialing
+		if err == nil {
+			cl.Close()
+		}
+		conn.err = fmt.Errorf("unable to connect to chain %s: the chains are closed", ch.Name)
+		return
+	}
+	if err != nil {
+		delete(c.connections, ch.Name)
+		conn.err = fmt.Errorf("unable to connect to chain %s: %w", ch.Name, err)
+		return
+	}
+	cl.StartHealthChecks(c.ctx, client.DefaultHealthCheckInterval)
+	c.clients = append(c.clients, cl)
+
+	waiter := util.NewWaiter(cl, time.Duration(ch.BlockTime), len(ch.WsUrls) > 0)
+	c.stopWaiters = append(c.stopWaiters, waiter.Start(c.ctx))
+	conn.client, conn.waiter = cl, waiter
+}
+
+// Close stops the waiters and closes the clients created by the chains.
+func (c *Chains) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, stop := range c.stopWaiters {
+		stop()
+	}
+	for _, cl := range c.clients {
+		cl.Close()
+	}
+	c.stopWaiters = nil
+	c.clients = nil
+	c.connections = make(map[string]*connection)
+}
@@ -0,0 +1,210 @@
+package activity
+
+import (
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/client/clienttest"
+	"context"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFakeRegistry returns a registry of two chains served by fake nodes, alpha with LayerZero chain id 101 and
+// beta with 102.
+func newFakeRegistry(t *testing.T) *chain.Registry {
+	chains := []*chain.Chain{
+		{Name: "alpha", ChainId: 1001, LayerZeroChainId: 101, NativeSymbol: "ALP"},
+		{Name: "beta", ChainId: 1002, LayerZeroChainId: 102, NativeSymbol: "BET"},
+	}
+	for _, c := range chains {
+		c.RpcUrls = []string{clienttest.Serve(t, &fakeEth{chainId: int64(c.ChainId)})}
+	}
+	return chain.NewRegistry(chains...)
+}
+
+// countingTransactors returns a transactor factory signing with a fresh key, counting the transactors created.
+func countingTransactors(t *testing.T) (TransactorFactory, *int) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := 0
+	lock := &sync.Mutex{}
+	return func(account accounts.Account, chainId *big.Int) (*bind.TransactOpts, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		created++
+		return bind.NewKeyedTransactorWithChainID(key, chainId)
+	}, &created
+}
+
+func TestChainsContext(t *testing.T) {
+	newTransactor, created := countingTransactors(t)
+	chains := NewChains(context.Background(), newFakeRegistry(t), newTransactor)
+	t.Cleanup(chains.Close)
+	chains.SetSigner(func(account accounts.Account, hash []byte) ([]byte, error) { return nil, nil })
+
+	first, second := &accounts.Account{}, &accounts.Account{}
+	second.Address[0] = 1
+	ac, err := chains.Context(context.Background(), "alpha", first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ac.Chain.Name != "alpha" || ac.Account != first || ac.Chains != chains || ac.Signer == nil {
+		t.Errorf("Context() = %+v, want alpha for the first account with the chains and signer", ac)
+	}
+	chainId, err := ac.Client.ChainID(ac.Context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chainId.Uint64() != 1001 {
+		t.Errorf("client chain id = %d, want 1001", chainId)
+	}
+
+	again, err := chains.Context(context.Background(), "alpha", first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := chains.Context(context.Background(), "alpha", second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Client != ac.Client || other.Client != ac.Client || other.Waiter != ac.Waiter {
+		t.Error("contexts of the same chain do not share the client and waiter")
+	}
+	if again.Transactor != ac.Transactor || other.Transactor == ac.Transactor || *created != 2 {
+		t.Errorf("created %d transactors, want one per account", *created)
+	}
+
+	if _, err := chains.Context(context.Background(), "gamma", first); err == nil {
+		t.Error("Context() on an unknown chain succeeded, want an error")
+	}
+}
+
+func TestChainsContextDialsOutsideTheLock(t *testing.T) {
+	registry := newFakeRegistry(t)
+	// The second endpoint of beta holds the websocket handshake until released, then refuses it
+	release := make(chan struct{})
+	releaseOnce := &sync.Once{}
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		http.Error(w, "no websocket", http.StatusNotFound)
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { releaseOnce.Do(func() { close(release) }) })
+	beta, err := registry.Get("beta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	beta.RpcUrls = append(beta.RpcUrls, "ws"+slow.URL[len("http"):])
+
+	newTransactor, _ := countingTransactors(t)
+	chains := NewChains(context.Background(), registry, newTransactor)
+	t.Cleanup(chains.Close)
+
+	dialed := make(chan ActivityContext, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			ac, err := chains.Context(context.Background(), "beta", &accounts.Account{})
+			if err != nil {
+				t.Error(err)
+			}
+			dialed <- ac
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := chains.Context(context.Background(), "alpha", &accounts.Account{})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Context() on alpha waited for the dial of beta")
+	}
+
+	releaseOnce.Do(func() { close(release) })
+	first, second := <-dialed, <-dialed
+	if first.Client == nil || first.Client != second.Client {
+		t.Error("concurrent contexts of beta do not share a single dial")
+	}
+}
+
+func TestOnChain(t *testing.T) {
+	newTransactor, _ := countingTransactors(t)
+	chains := NewChains(context.Background(), newFakeRegistry(t), newTransactor)
+	t.Cleanup(chains.Close)
+	account := &accounts.Account{}
+	ac, err := chains.Context(context.Background(), "alpha", account)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same, err := ac.OnChain("alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same.Client != ac.Client || same.Transactor != ac.Transactor {
+		t.Error("OnChain() of the same chain returned another context")
+	}
+	beta, err := ac.OnChain("beta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if beta.Chain.Name != "beta" || beta.Account != account || beta.Client == ac.Client {
+		t.Errorf("OnChain(beta) = %+v, want the account on beta", beta)
+	}
+	if _, err := ac.OnChain("gamma"); err == nil {
+		t.Error("OnChain() on an unknown chain succeeded, want an error")
+	}
+
+	standalone := ActivityContext{Account: account, Chain: ac.Chain, Context: context.Background()}
+	if _, err := standalone.OnChain("beta"); err == nil {
+		t.Error("OnChain() without chains succeeded, want an error")
+	}
+}
+
+func TestOnLayerZeroChain(t *testing.T) {
+	newTransactor, _ := countingTransactors(t)
+	chains := NewChains(context.Background(), newFakeRegistry(t), newTransactor)
+	t.Cleanup(chains.Close)
+	account := &accounts.Account{}
+	ac, err := chains.Context(context.Background(), "alpha", account)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same, err := ac.OnLayerZeroChain(101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same.Client != ac.Client {
+		t.Error("OnLayerZeroChain() of the same chain returned another context")
+	}
+	beta, err := ac.OnLayerZeroChain(102)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if beta.Chain.Name != "beta" || beta.Account != account {
+		t.Errorf("OnLayerZeroChain(102) = %+v, want the account on beta", beta)
+	}
+	if _, err := ac.OnLayerZeroChain(103); err == nil {
+		t.Error("OnLayerZeroChain() of an unknown chain id succeeded, want an error")
+	}
+
+	standalone := ActivityContext{Account: account, Chain: ac.Chain, Context: context.Background()}
+	if _, err := standalone.OnLayerZeroChain(102); err == nil {
+		t.Error("OnLayerZeroChain() without chains succeeded, want an error")
+	}
+}
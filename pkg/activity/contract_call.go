@@ -0,0 +1,682 @@
+package activity
+
+import (
+	"activity-bot/pkg/abi/erc20"
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/random"
+	"activity-bot/pkg/token"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Kinds of contract call arguments.
+const (
+	ArgAccount  = "account"  // Address of the account
+	ArgConstant = "constant" // Value parsed according to the ABI type of the argument
+	ArgRandom   = "random"   // Integer drawn between Min and Max, or a Token amount
+	ArgToken    = "token"    // Address of the token with the Value symbol in the chain's token book
+	ArgContract = "contract" // Address of the Value contract in the chain's contract book
+	ArgDeadline = "deadline" // Unix time in the Value duration from now, such as "5m"
+	ArgPermit   = "permit"   // Value field of the signed permit: "value", "deadline", "v", "r" or "s"
+)
+
+// ArgSpec declares how the value of a contract call argument is built at runtime.
+type ArgSpec struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value,omitempty"`
+	Min   string `json:"min,omitempty"` // Bounds of random arguments, integers or decimal amounts of Token
+	Max   string `json:"max,omitempty"`
+	Token string `json:"token,omitempty"` // Optional symbol of the chain's token book random bounds are amounts of
+}
+
+// AmountSpec is an amount of a token, either a constant decimal amount or the value of an integer argument of
+// the call in the minimal unit.
+type AmountSpec struct {
+	Amount string `json:"amount,omitempty"` // Decimal amount, such as "12.5"
+	Arg    *int   `json:"arg,omitempty"`    // Index of the argument
+}
+
+// ApprovalSpec declares an ERC-20 allowance the call needs.
+type ApprovalSpec struct {
+	AmountSpec
+	Token   string `json:"token"`             // Symbol in the chain's token book
+	Spender string `json:"spender,omitempty"` // Contract book name or address, defaults to the called contract
+	Permit  bool   `json:"permit,omitempty"`  // The method takes an EIP-2612 permit through permit arguments
+	// Method of the same contract called with the arguments other than the permit ones when no permit is
+	// signed, because the allowance is already enough or the token does not support permits. Without it a
+	// permit is always signed.
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// BalanceSpec declares a balance the account needs for the call.
+type BalanceSpec struct {
+	AmountSpec
+	Token string `json:"token,omitempty"` // Symbol in the chain's token book, the native token when empty
+}
+
+// ContractCallSpec declares a contract call, it is typically read from a JSON file.
+type ContractCallSpec struct {
+	Chain     string         `json:"chain"`
+	Contract  string         `json:"contract"` // Contract book name, token symbol or address
+	Abi       string         `json:"abi"`      // Path of the ABI JSON file, such as abi/WooRouterAvax.json
+	Method    string         `json:"method"`
+	Args      []ArgSpec      `json:"args"`
+	Value     *ArgSpec       `json:"value,omitempty"`    // Optional native value in wei, constant or random
+	GasLimit  uint64         `json:"gasLimit,omitempty"` // Estimated when zero
+	Approvals []ApprovalSpec `json:"approvals,omitempty"`
+	Balances  []BalanceSpec  `json:"balances,omitempty"`
+}
+
+// LoadContractCallSpecs reads the contract call specs of a JSON file holding an array of specs.
+func LoadContractCallSpecs(path string) ([]ContractCallSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var specs []ContractCallSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("invalid contract calls %s: %w", path, err)
+	}
+	return specs, nil
+}
+
+// ContractCall calls a contract method from its ABI JSON, the arguments are packed at runtime so no binding
+// is needed.
+type ContractCall struct {
+	Spec     ContractCallSpec
+	abi      abi.ABI
+	method   abi.Method
+	fallback *abi.Method
+	contract common.Address          // Computed on can execute
+	tokens   map[string]*token.Token // Computed on can execute, by symbol of the approvals and balances
+	args     []interface{}           // Computed on can execute
+	value    *big.Int                // Computed on can execute
+}
+
+func NewContractCall(spec ContractCallSpec) (*ContractCall, error) {
+	file, err := os.Open(spec.Abi)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	parsed, err := abi.JSON(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ABI %s: %w", spec.Abi, err)
+	}
+	method, ok := parsed.Methods[spec.Method]
+	if !ok {
+		return nil, fmt.Errorf("method %s is not in ABI %s", spec.Method, spec.Abi)
+	}
+	if method.IsConstant() {
+		return nil, fmt.Errorf("method %s of ABI %s does not send a transaction", spec.Method, spec.Abi)
+	}
+	if len(spec.Args) != len(method.Inputs) {
+		return nil, fmt.Errorf("method %s takes %d arguments, got %d", spec.Method, len(method.Inputs), len(spec.Args))
+	}
+	for i, arg := range spec.Args {
+		if err := checkArgSpec(arg, method.Inputs[i].Type); err != nil {
+			return nil, fmt.Errorf("argument %d of %s: %w", i, spec.Method, err)
+		}
+	}
+	if spec.Value != nil {
+		if !method.IsPayable() {
+			return nil, fmt.Errorf("method %s is not payable", spec.Method)
+		}
+		if spec.Value.Kind != ArgConstant && spec.Value.Kind != ArgRandom {
+			return nil, fmt.Errorf("value of %s must be a constant or random amount, got %s", spec.Method, spec.Value.Kind)
+		}
+		if spec.Value.Token != "" {
+			return nil, fmt.Errorf("value of %s is in wei, it can not be an amount of %s", spec.Method, spec.Value.Token)
+		}
+	}
+	if err := checkPermitSpecs(spec); err != nil {
+		return nil, err
+	}
+	fallback, err := fallbackMethod(parsed, spec)
+	if err != nil {
+		return nil, err
+	}
+	for _, amount := range spec.amounts() {
+		if amount.Arg == nil {
+			continue
+		}
+		if *amount.Arg < 0 || *amount.Arg >= len(spec.Args) {
+			return nil, fmt.Errorf("method %s has no argument %d", spec.Method, *amount.Arg)
+		}
+		if t := method.Inputs[*amount.Arg].Type; t.T != abi.UintTy && t.T != abi.IntTy {
+			return nil, fmt.Errorf("argument %d of %s is a %s, amounts must be integers", *amount.Arg, spec.Method, t.String())
+		}
+		// Permit and deadline arguments are only known once the amount they depend on is approved
+		if kind := spec.Args[*amount.Arg].Kind; kind == ArgPermit || kind == ArgDeadline {
+			return nil, fmt.Errorf("argument %d of %s is a %s argument, it can not be an amount", *amount.Arg, spec.Method, kind)
+		}
+	}
+	return &ContractCall{
+		Spec:     spec,
+		abi:      parsed,
+		method:   method,
+		fallback: fallback,
+	}, nil
+}
+
+func (spec ContractCallSpec) amounts() []AmountSpec {
+	amounts := make([]AmountSpec, 0, len(spec.Approvals)+len(spec.Balances))
+	for _, approval := range spec.Approvals {
+		amounts = append(amounts, approval.AmountSpec)
+	}
+	for _, balance := range spec.Balances {
+		amounts = append(amounts, balance.AmountSpec)
+	}
+	return amounts
+}
+
+func (c *ContractCall) Chain() string {
+	return c.Spec.Chain
+}
+
+func (c *ContractCall) Contracts(ch *chain.Chain) ([]common.Address, error) {
+	contract, err := resolveAddress(ch, c.Spec.Contract)
+	if err != nil {
+		return nil, err
+	}
+	contracts := []common.Address{contract}
+	for _, approval := range c.Spec.Approvals {
+		token, err := ch.Token(approval.Token)
+		if err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, token)
+		if approval.Spender != "" {
+			spender, err := resolveAddress(ch, approval.Spender)
+			if err != nil {
+				return nil, err
+			}
+			contracts = append(contracts, spender)
+		}
+	}
+	return contracts, nil
+}
+
+func (c *ContractCall) CanExecute(ac ActivityContext) (bool, error) {
+	var err error
+	c.contract, err = resolveAddress(ac.Chain, c.Spec.Contract)
+	if err != nil {
+		return false, err
+	}
+
+	c.tokens = make(map[string]*token.Token)
+	for _, approval := range c.Spec.Approvals {
+		if c.tokens[approval.Token], err = token.Resolve(ac.CallOpts(), ac.Chain, ac.Client, approval.Token); err != nil {
+			return false, err
+		}
+	}
+	for _, balance := range c.Spec.Balances {
+		if balance.Token == "" {
+			c.tokens[balance.Token] = token.Native(ac.Chain)
+		} else if c.tokens[balance.Token], err = token.Resolve(ac.CallOpts(), ac.Chain, ac.Client, balance.Token); err != nil {
+			return false, err
+		}
+	}
+
+	c.args = make([]interface{}, len(c.Spec.Args))
+	for i, arg := range c.Spec.Args {
+		// Deadlines are built on execute, once the approvals are mined
+		if arg.Kind == ArgDeadline {
+			continue
+		}
+		c.args[i], err = buildArg(ac, arg, c.method.Inputs[i].Type)
+		if err != nil {
+			return false, fmt.Errorf("argument %d of %s: %w", i, c.Spec.Method, err)
+		}
+	}
+	c.value = big.NewInt(0)
+	if c.Spec.Value != nil {
+		value, err := buildArg(ac, *c.Spec.Value, abi.Type{T: abi.UintTy, Size: 256})
+		if err != nil {
+			return false, fmt.Errorf("value of %s: %w", c.Spec.Method, err)
+		}
+		c.value = value.(*big.Int)
+	}
+
+	for _, balance := range c.Spec.Balances {
+		required, err := c.amount(balance.AmountSpec, c.tokens[balance.Token])
+		if err != nil {
+			return false, err
+		}
+		var available *big.Int
+		symbol := balance.Token
+		if symbol == "" {
+			symbol = ac.Chain.NativeSymbol
+			available, err = ac.Client.BalanceAt(ac.Context, ac.Account.Address, nil)
+		} else {
+			var token *erc20.Erc20
+			token, err = c.token(ac, balance.Token)
+			if err != nil {
+				return false, err
+			}
+			available, err = token.BalanceOf(ac.CallOpts(), ac.Account.Address)
+		}
+		if err != nil {
+			return false, errors.New(fmt.Sprintf("Error getting account %s balance [%s]: %v", symbol, ac.Account.Address.Hex(), err))
+		}
+		if available.Cmp(required) < 0 {
+			return false, errors.New(fmt.Sprintf("Account [%s] has not enough %s balance to call %s", ac.Account.Address.Hex(), symbol, c.Spec.Method))
+		}
+	}
+
+	return true, nil
+}
+
+func (c *ContractCall) Execute(ac ActivityContext) (bool, error) {
+	ac.Transactor.Context = ac.Context
+	log.Printf("[%s] started calling %s on %s\n", ac.Account.Address.Hex(), c.Spec.Method, c.contract.Hex())
+	useFallback := false
+
+	for _, approval := range c.Spec.Approvals {
+		amount, err := c.amount(approval.AmountSpec, c.tokens[approval.Token])
+		if err != nil {
+			return false, err
+		}
+		spender := c.contract
+		if approval.Spender != "" {
+			spender, err = resolveAddress(ac.Chain, approval.Spender)
+			if err != nil {
+				return false, err
+			}
+		}
+		if approval.Permit {
+			tokenAddress, err := ac.Chain.Token(approval.Token)
+			if err != nil {
+				return false, err
+			}
+			var permit *Permit
+			if c.fallback == nil {
+				permit, err = signPermit(ac, tokenAddress, spender, amount, DefaultDeadline)
+			} else {
+				permit, err = approveOrPermit(ac, tokenAddress, spender, amount, true)
+			}
+			if err != nil {
+				return false, err
+			}
+			if permit == nil {
+				useFallback = true
+				continue
+			}
+			if err := c.fillPermit(permit); err != nil {
+				return false, err
+			}
+			continue
+		}
+		token, err := c.token(ac, approval.Token)
+		if err != nil {
+			return false, err
+		}
+		log.Printf("Checking if %s allowance required\n", approval.Token)
+		if err := ensureAllowance(ac, token, spender, amount); err != nil {
+			return false, err
+		}
+	}
+
+	if err := c.buildDeadlines(ac); err != nil {
+		return false, err
+	}
+	method, args := c.Spec.Method, c.args
+	if useFallback {
+		method, args = c.fallback.Name, c.fallbackArgs()
+	}
+	contract := bind.NewBoundContract(c.contract, c.abi, ac.Client, ac.Client, ac.Client)
+	ac.Transactor.Value = c.value
+	ac.Transactor.GasLimit = c.Spec.GasLimit
+	tx, err := contract.Transact(ac.Transactor, method, args...)
+	ac.Transactor.Value = big.NewInt(0)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("%s tx sent: %s", method, tx.Hash().Hex())
+	receipt, err := ac.WaitForReceipt(tx)
+	if err != nil {
+		return false, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return false, errors.New(fmt.Sprintf("%s tx failed: %s", method, receipt.TxHash.Hex()))
+	}
+	return true, nil
+}
+
+// buildDeadlines sets the deadline arguments, counted from now so the approvals do not eat into them.
+func (c *ContractCall) buildDeadlines(ac ActivityContext) error {
+	for i, arg := range c.Spec.Args {
+		if arg.Kind != ArgDeadline {
+			continue
+		}
+		var err error
+		c.args[i], err = buildArg(ac, arg, c.method.Inputs[i].Type)
+		if err != nil {
+			return fmt.Errorf("argument %d of %s: %w", i, c.Spec.Method, err)
+		}
+	}
+	return nil
+}
+
+// fillPermit sets the permit arguments from the signed permit.
+func (c *ContractCall) fillPermit(permit *Permit) error {
+	for i, arg := range c.Spec.Args {
+		if arg.Kind != ArgPermit {
+			continue
+		}
+		t := c.method.Inputs[i].Type
+		var err error
+		switch arg.Value {
+		case "value":
+			c.args[i], err = convertInteger(t, permit.Value)
+		case "deadline":
+			c.args[i], err = convertInteger(t, permit.Deadline)
+		case "v":
+			c.args[i] = permit.V
+		case "r":
+			c.args[i] = permit.R
+		case "s":
+			c.args[i] = permit.S
+		}
+		if err != nil {
+			return fmt.Errorf("argument %d of %s: %w", i, c.Spec.Method, err)
+		}
+	}
+	return nil
+}
+
+// fallbackArgs returns the arguments of the fallback method, the arguments other than the permit ones.
+func (c *ContractCall) fallbackArgs() []interface{} {
+	args := make([]interface{}, 0, len(c.args))
+	for i, arg := range c.Spec.Args {
+		if arg.Kind != ArgPermit {
+			args = append(args, c.args[i])
+		}
+	}
+	return args
+}
+
+// amount returns the constant amount of the token or the value of the argument computed on can execute, in the
+// minimal unit.
+func (c *ContractCall) amount(spec AmountSpec, t *token.Token) (*big.Int, error) {
+	if spec.Arg == nil {
+		return t.Parse(spec.Amount)
+	}
+	amount, ok := integerValue(c.args[*spec.Arg])
+	if !ok {
+		return nil, fmt.Errorf("argument %d of %s is not an amount", *spec.Arg, c.Spec.Method)
+	}
+	return amount, nil
+}
+
+// integerValue returns the integer packed as any of the Go types convertInteger returns.
+func integerValue(value interface{}) (*big.Int, bool) {
+	if amount, ok := value.(*big.Int); ok {
+		return amount, amount != nil
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(v.Uint()), true
+	}
+	return nil, false
+}
+
+func (c *ContractCall) token(ac ActivityContext, symbol string) (*erc20.Erc20, error) {
+	address, err := ac.Chain.Token(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return erc20.NewErc20(address, ac.Client)
+}
+
+// resolveAddress resolves a contract book name, a token symbol or an address.
+func resolveAddress(c *chain.Chain, ref string) (common.Address, error) {
+	if common.IsHexAddress(ref) {
+		return common.HexToAddress(ref), nil
+	}
+	if address, err := c.Contract(ref); err == nil {
+		return address, nil
+	}
+	if address, err := c.Token(ref); err == nil {
+		return address, nil
+	}
+	return common.Address{}, fmt.Errorf("%s is neither an address nor in the address book of chain %s", ref, c.Name)
+}
+
+// checkArgSpec checks that the argument kind fits the ABI type, before anything is resolved.
+func checkArgSpec(arg ArgSpec, t abi.Type) error {
+	if arg.Token != "" && arg.Kind != ArgRandom {
+		return fmt.Errorf("%s arguments can not be an amount of %s", arg.Kind, arg.Token)
+	}
+	switch arg.Kind {
+	case ArgAccount, ArgToken, ArgContract:
+		if t.T != abi.AddressTy {
+			return fmt.Errorf("%s arguments are addresses, the ABI expects %s", arg.Kind, t.String())
+		}
+	case ArgRandom, ArgDeadline:
+		if t.T != abi.UintTy && t.T != abi.IntTy {
+			return fmt.Errorf("%s arguments are integers, the ABI expects %s", arg.Kind, t.String())
+		}
+		if arg.Kind == ArgDeadline {
+			_, err := time.ParseDuration(arg.Value)
+			return err
+		}
+	case ArgConstant:
+		_, err := parseArg(t, arg.Value)
+		return err
+	case ArgPermit:
+		_, err := permitArg(arg.Value, t)
+		return err
+	default:
+		return fmt.Errorf("unknown argument kind %q", arg.Kind)
+	}
+	return nil
+}
+
+// buildArg returns the value of the argument for the account, typed as the ABI packer expects.
+func buildArg(ac ActivityContext, arg ArgSpec, t abi.Type) (interface{}, error) {
+	switch arg.Kind {
+	case ArgAccount:
+		return ac.Account.Address, nil
+	case ArgToken:
+		return ac.Chain.Token(arg.Value)
+	case ArgContract:
+		return ac.Chain.Contract(arg.Value)
+	case ArgConstant:
+		return parseArg(t, arg.Value)
+	case ArgRandom:
+		if arg.Token != "" {
+			tk, err := token.Resolve(ac.CallOpts(), ac.Chain, ac.Client, arg.Token)
+			if err != nil {
+				return nil, err
+			}
+			supplier, err := tk.Supplier(arg.Min, arg.Max)
+			if err != nil {
+				return nil, err
+			}
+			return convertInteger(t, supplier.Supply())
+		}
+		min, ok := new(big.Int).SetString(arg.Min, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid minimum %q", arg.Min)
+		}
+		max, ok := new(big.Int).SetString(arg.Max, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid maximum %q", arg.Max)
+		}
+		if min.Cmp(max) > 0 {
+			return nil, fmt.Errorf("minimum %s is above maximum %s", min.String(), max.String())
+		}
+		return convertInteger(t, random.NewRangeSupplier(min, max).Supply())
+	case ArgPermit:
+		return permitArg(arg.Value, t)
+	case ArgDeadline:
+		duration, err := time.ParseDuration(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		return convertInteger(t, big.NewInt(time.Now().Add(duration).Unix()))
+	}
+	return nil, fmt.Errorf("unknown argument kind %q", arg.Kind)
+}
+
+// checkPermitSpecs checks that permit arguments come with exactly one permit approval.
+func checkPermitSpecs(spec ContractCallSpec) error {
+	permits := 0
+	for _, approval := range spec.Approvals {
+		if approval.Permit {
+			permits++
+		}
+	}
+	if permits > 1 {
+		return fmt.Errorf("method %s can take one permit, got %d", spec.Method, permits)
+	}
+	for _, arg := range spec.Args {
+		if arg.Kind == ArgPermit && permits == 0 {
+			return fmt.Errorf("method %s takes permit arguments without permit approval", spec.Method)
+		}
+	}
+	return nil
+}
+
+// fallbackMethod returns the method called instead of the permit one when no permit is signed, it must take the
+// arguments other than the permit ones.
+func fallbackMethod(parsed abi.ABI, spec ContractCallSpec) (*abi.Method, error) {
+	name := ""
+	for _, approval := range spec.Approvals {
+		if approval.Fallback == "" {
+			continue
+		}
+		if !approval.Permit {
+			return nil, fmt.Errorf("fallback %s of %s is only called in place of a permit", approval.Fallback, spec.Method)
+		}
+		name = approval.Fallback
+	}
+	if name == "" {
+		return nil, nil
+	}
+	method, ok := parsed.Methods[name]
+	if !ok {
+		return nil, fmt.Errorf("fallback method %s is not in ABI %s", name, spec.Abi)
+	}
+	if method.IsConstant() {
+		return nil, fmt.Errorf("fallback method %s of ABI %s does not send a transaction", name, spec.Abi)
+	}
+	if spec.Value != nil && !method.IsPayable() {
+		return nil, fmt.Errorf("fallback method %s is not payable", name)
+	}
+	// The arguments are built for the permit method so they must keep their types
+	inputs := make([]abi.Type, 0, len(spec.Args))
+	for i, arg := range spec.Args {
+		if arg.Kind != ArgPermit {
+			inputs = append(inputs, parsed.Methods[spec.Method].Inputs[i].Type)
+		}
+	}
+	if len(inputs) != len(method.Inputs) {
+		return nil, fmt.Errorf("fallback method %s takes %d arguments, %s has %d without permit", name, len(method.Inputs), spec.Method, len(inputs))
+	}
+	for i, input := range inputs {
+		if method.Inputs[i].Type.String() != input.String() {
+			return nil, fmt.Errorf("argument %d of fallback method %s is a %s, %s passes a %s", i, name, method.Inputs[i].Type.String(), spec.Method, input.String())
+		}
+	}
+	return &method, nil
+}
+
+// permitArg returns the empty value of a permit argument, filled once the permit is signed.
+func permitArg(field string, t abi.Type) (interface{}, error) {
+	switch field {
+	case "value", "deadline":
+		if t.T != abi.UintTy {
+			return nil, fmt.Errorf("permit %s is an unsigned integer, the ABI expects %s", field, t.String())
+		}
+		return convertInteger(t, big.NewInt(0))
+	case "v":
+		if t.T != abi.UintTy || t.Size != 8 {
+			return nil, fmt.Errorf("permit v is an uint8, the ABI expects %s", t.String())
+		}
+		return uint8(0), nil
+	case "r", "s":
+		if t.T != abi.FixedBytesTy || t.Size != 32 {
+			return nil, fmt.Errorf("permit %s is a bytes32, the ABI expects %s", field, t.String())
+		}
+		return [32]byte{}, nil
+	}
+	return nil, fmt.Errorf("unknown permit field %q", field)
+}
+
+// parseArg parses a constant of an elementary ABI type.
+func parseArg(t abi.Type, s string) (interface{}, error) {
+	switch t.T {
+	case abi.AddressTy:
+		if !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("invalid address %q", s)
+		}
+		return common.HexToAddress(s), nil
+	case abi.UintTy, abi.IntTy:
+		value, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", s)
+		}
+		return convertInteger(t, value)
+	case abi.BoolTy:
+		return strconv.ParseBool(s)
+	case abi.StringTy:
+		return s, nil
+	case abi.BytesTy:
+		return hexutil.Decode(s)
+	case abi.FixedBytesTy:
+		data, err := hexutil.Decode(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) != t.Size {
+			return nil, fmt.Errorf("%s needs %d bytes, got %d", t.String(), t.Size, len(data))
+		}
+		value := reflect.New(t.GetType()).Elem()
+		reflect.Copy(value, reflect.ValueOf(data))
+		return value.Interface(), nil
+	}
+	return nil, fmt.Errorf("constants of type %s are not supported", t.String())
+}
+
+// convertInteger converts the integer to the Go type the ABI packer expects for the integer type, *big.Int
+// above 64 bits.
+func convertInteger(t abi.Type, value *big.Int) (interface{}, error) {
+	if t.T == abi.UintTy && value.Sign() < 0 {
+		return nil, fmt.Errorf("%s can not hold %s", t.String(), value.String())
+	}
+	bits := t.Size
+	if t.T == abi.IntTy {
+		bits-- // Sign bit
+	}
+	if value.BitLen() > bits {
+		return nil, fmt.Errorf("%s can not hold %s", t.String(), value.String())
+	}
+	goType := t.GetType()
+	if goType == reflect.TypeOf(value) {
+		return value, nil
+	}
+	converted := reflect.New(goType).Elem()
+	if t.T == abi.UintTy {
+		converted.SetUint(value.Uint64())
+	} else {
+		converted.SetInt(value.Int64())
+	}
+	return converted.Interface(), nil
+}
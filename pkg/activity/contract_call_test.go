@@ -0,0 +1,440 @@
+package activity
+
+import (
+	"activity-bot/pkg/chain"
+	"bytes"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseArg(t *testing.T) {
+	uint16Ty, _ := abi.NewType("uint16", "", nil)
+	uint256Ty, _ := abi.NewType("uint256", "", nil)
+	int8Ty, _ := abi.NewType("int8", "", nil)
+	bytes4Ty, _ := abi.NewType("bytes4", "", nil)
+	addressTy, _ := abi.NewType("address", "", nil)
+
+	tests := []struct {
+		name    string
+		t       abi.Type
+		value   string
+		want    interface{}
+		wantErr bool
+	}{
+		{"uint16", uint16Ty, "106", uint16(106), false},
+		{"uint16 overflow", uint16Ty, "65536", nil, true},
+		{"uint256 hex", uint256Ty, "0xff", big.NewInt(255), false},
+		{"negative uint", uint256Ty, "-1", nil, true},
+		{"int8", int8Ty, "-5", int8(-5), false},
+		{"bytes4", bytes4Ty, "0x095ea7b3", [4]byte{0x09, 0x5e, 0xa7, 0xb3}, false},
+		{"bytes4 too short", bytes4Ty, "0x095e", nil, true},
+		{"address", addressTy, "0x2297aEbD383787A160DD0d9F71508148769342E3", common.HexToAddress("0x2297aEbD383787A160DD0d9F71508148769342E3"), false},
+		{"invalid address", addressTy, "bitcoinBridge", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseArg(tt.t, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseArg() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseArg() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContractCallBuildsArgs(t *testing.T) {
+	amountArg := 1
+	call, err := NewContractCall(ContractCallSpec{
+		Chain:    chain.Avalanche,
+		Contract: "USDC",
+		Abi:      "../../abi/Erc20.json",
+		Method:   "approve",
+		Args: []ArgSpec{
+			{Kind: ArgContract, Value: chain.StargateRouter},
+			{Kind: ArgRandom, Min: "1000", Max: "2000"},
+		},
+		Balances: []BalanceSpec{{AmountSpec: AmountSpec{Arg: &amountArg}, Token: "USDC"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	avalanche, err := chain.DefaultRegistry().Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ac := ActivityContext{Account: &accounts.Account{}, Chain: avalanche}
+
+	spender, err := buildArg(ac, call.Spec.Args[0], call.method.Inputs[0].Type)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spender != avalanche.Contracts[chain.StargateRouter] {
+		t.Errorf("spender = %v, want the Stargate router", spender)
+	}
+	amount, err := buildArg(ac, call.Spec.Args[1], call.method.Inputs[1].Type)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := amount.(*big.Int); a.Cmp(big.NewInt(1000)) < 0 || a.Cmp(big.NewInt(2000)) > 0 {
+		t.Errorf("amount = %v, want within [1000, 2000]", a)
+	}
+
+	if _, err := NewContractCall(ContractCallSpec{Abi: "../../abi/Erc20.json", Method: "balanceOf", Args: []ArgSpec{{Kind: ArgAccount}}}); err == nil {
+		t.Error("NewContractCall() error = nil, want an error for a view method")
+	}
+	if _, err := NewContractCall(ContractCallSpec{Abi: "../../abi/Erc20.json", Method: "approve", Args: []ArgSpec{{Kind: ArgRandom}, {Kind: ArgAccount}}}); err == nil {
+		t.Error("NewContractCall() error = nil, want an error for mismatching argument kinds")
+	}
+}
+
+func TestContractCallFillsPermit(t *testing.T) {
+	permitAbi := "../../abi/Erc20Permit.json"
+	args := []ArgSpec{
+		{Kind: ArgAccount},
+		{Kind: ArgContract, Value: chain.StargateRouter},
+		{Kind: ArgPermit, Value: "value"},
+		{Kind: ArgPermit, Value: "deadline"},
+		{Kind: ArgPermit, Value: "v"},
+		{Kind: ArgPermit, Value: "r"},
+		{Kind: ArgPermit, Value: "s"},
+	}
+	if _, err := NewContractCall(ContractCallSpec{Abi: permitAbi, Method: "permit", Args: args}); err == nil {
+		t.Error("NewContractCall() error = nil, want an error for permit arguments without permit approval")
+	}
+	call, err := NewContractCall(ContractCallSpec{
+		Abi:       permitAbi,
+		Method:    "permit",
+		Args:      args,
+		Approvals: []ApprovalSpec{{AmountSpec: AmountSpec{Amount: "100"}, Token: "USDC", Permit: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	call.args = make([]interface{}, len(args))
+	permit := &Permit{Value: big.NewInt(100), Deadline: big.NewInt(1700000000), V: 28, R: [32]byte{1}, S: [32]byte{2}}
+	if err := call.fillPermit(permit); err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{nil, nil, big.NewInt(100), big.NewInt(1700000000), uint8(28), [32]byte{1}, [32]byte{2}}
+	if !reflect.DeepEqual(call.args, want) {
+		t.Errorf("args = %v, want %v", call.args, want)
+	}
+}
+
+func TestContractCallAmountOfSmallIntegers(t *testing.T) {
+	call := &ContractCall{args: []interface{}{uint16(106), int8(-5), uint64(1 << 40), big.NewInt(1000), common.Address{}}}
+	tests := []struct {
+		arg     int
+		want    int64
+		wantErr bool
+	}{
+		{0, 106, false},
+		{1, -5, false},
+		{2, 1 << 40, false},
+		{3, 1000, false},
+		{4, 0, true},
+	}
+	for _, tt := range tests {
+		arg := tt.arg
+		got, err := call.amount(AmountSpec{Arg: &arg}, nil)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("amount(%d) error = %v, wantErr %v", arg, err, tt.wantErr)
+		}
+		if !tt.wantErr && got.Int64() != tt.want {
+			t.Errorf("amount(%d) = %v, want %d", arg, got, tt.want)
+		}
+	}
+}
+
+func TestNewContractCallChecksAmountArgs(t *testing.T) {
+	spenderArg, permitValueArg := 0, 2
+	permitArgs := []ArgSpec{
+		{Kind: ArgAccount},
+		{Kind: ArgContract, Value: chain.StargateRouter},
+		{Kind: ArgPermit, Value: "value"},
+		{Kind: ArgPermit, Value: "deadline"},
+		{Kind: ArgPermit, Value: "v"},
+		{Kind: ArgPermit, Value: "r"},
+		{Kind: ArgPermit, Value: "s"},
+	}
+	tests := []struct {
+		name string
+		spec ContractCallSpec
+	}{
+		{
+			name: "address argument",
+			spec: ContractCallSpec{
+				Abi:      "../../abi/Erc20.json",
+				Method:   "approve",
+				Args:     []ArgSpec{{Kind: ArgContract, Value: chain.StargateRouter}, {Kind: ArgConstant, Value: "100"}},
+				Balances: []BalanceSpec{{AmountSpec: AmountSpec{Arg: &spenderArg}}},
+			},
+		},
+		{
+			name: "permit argument",
+			spec: ContractCallSpec{
+				Abi:       "../../abi/Erc20Permit.json",
+				Method:    "permit",
+				Args:      permitArgs,
+				Approvals: []ApprovalSpec{{AmountSpec: AmountSpec{Arg: &permitValueArg}, Token: "USDC", Permit: true}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewContractCall(tt.spec); err == nil {
+				t.Error("NewContractCall() error = nil, want an error")
+			}
+		})
+	}
+}
+
+// vaultAbi has a deposit taking a permit, a plain deposit to fall back to and a deposit with a deadline.
+const vaultAbi = `[
+	{"type": "function", "name": "depositWithPermit", "stateMutability": "nonpayable", "outputs": [], "inputs": [
+		{"name": "token", "type": "address"}, {"name": "amount", "type": "uint256"}, {"name": "deadline", "type": "uint256"},
+		{"name": "v", "type": "uint8"}, {"name": "r", "type": "bytes32"}, {"name": "s", "type": "bytes32"}]},
+	{"type": "function", "name": "deposit", "stateMutability": "nonpayable", "outputs": [], "inputs": [
+		{"name": "token", "type": "address"}, {"name": "amount", "type": "uint256"}]},
+	{"type": "function", "name": "depositFor", "stateMutability": "nonpayable", "outputs": [], "inputs": [
+		{"name": "token", "type": "address"}, {"name": "recipient", "type": "address"}]},
+	{"type": "function", "name": "depositBefore", "stateMutability": "nonpayable", "outputs": [], "inputs": [
+		{"name": "token", "type": "address"}, {"name": "amount", "type": "uint256"}, {"name": "deadline", "type": "uint256"}]}
+]`
+
+func vaultDepositSpec(t *testing.T, fallback string) ContractCallSpec {
+	path := filepath.Join(t.TempDir(), "Vault.json")
+	if err := os.WriteFile(path, []byte(vaultAbi), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	amountArg := 1
+	return ContractCallSpec{
+		Chain:    chain.Avalanche,
+		Contract: "0x1205f31718499dBf1fCa446663B532Ef87481fe1",
+		Abi:      path,
+		Method:   "depositWithPermit",
+		Args: []ArgSpec{
+			{Kind: ArgToken, Value: "USDC"},
+			{Kind: ArgConstant, Value: "100"},
+			{Kind: ArgPermit, Value: "deadline"},
+			{Kind: ArgPermit, Value: "v"},
+			{Kind: ArgPermit, Value: "r"},
+			{Kind: ArgPermit, Value: "s"},
+		},
+		Approvals: []ApprovalSpec{{AmountSpec: AmountSpec{Arg: &amountArg}, Token: "USDC", Permit: true, Fallback: fallback}},
+	}
+}
+
+func TestNewContractCallChecksFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback string
+		wantErr  bool
+	}{
+		{"arguments without permit", "deposit", false},
+		{"unknown method", "withdraw", true},
+		{"other argument types", "depositFor", true},
+		{"permit method", "depositWithPermit", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewContractCall(vaultDepositSpec(t, tt.fallback))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewContractCall() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	spec := vaultDepositSpec(t, "")
+	spec.Approvals[0].Permit = false
+	spec.Approvals[0].Fallback = "deposit"
+	spec.Args = spec.Args[:2]
+	spec.Method = "deposit"
+	if _, err := NewContractCall(spec); err == nil {
+		t.Error("NewContractCall() error = nil, want an error for a fallback without permit approval")
+	}
+}
+
+func TestContractCallWithoutPermit(t *testing.T) {
+	avalanche, err := chain.DefaultRegistry().Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vault := common.HexToAddress("0x1205f31718499dBf1fCa446663B532Ef87481fe1")
+	tests := []struct {
+		name     string
+		fallback string
+		wantErr  bool
+	}{
+		{name: "calls the fallback method", fallback: "deposit"},
+		{name: "fails without fallback method", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The allowance is enough so no permit is signed, the context has no signer either
+			node := newFakeErc20Node(avalanche)
+			node.allowances[allowanceKey{avalanche.Tokens["USDC"], vault}] = big.NewInt(1000)
+			ac := newAllowanceContext(t, node)
+
+			call, err := NewContractCall(vaultDepositSpec(t, tt.fallback))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok, err := call.CanExecute(ac); err != nil || !ok {
+				t.Fatalf("CanExecute() = %v, %v, want true", ok, err)
+			}
+			ok, err := call.Execute(ac)
+			if tt.wantErr {
+				if err == nil || len(node.sent) != 0 {
+					t.Errorf("Execute() = %v, %v with %d tx sent, want an error before sending", ok, err, len(node.sent))
+				}
+				return
+			}
+			if err != nil || !ok {
+				t.Fatalf("Execute() = %v, %v, want true", ok, err)
+			}
+			want, err := call.abi.Pack("deposit", avalanche.Tokens["USDC"], big.NewInt(100))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(node.sent) != 1 || !bytes.Equal(node.sent[0].Data(), want) {
+				t.Errorf("sent %d tx, want a single %s call", len(node.sent), "deposit")
+			}
+		})
+	}
+}
+
+func TestContractCallTokenAmounts(t *testing.T) {
+	avalanche, err := chain.DefaultRegistry().Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := newFakeErc20Node(avalanche)
+	ac := newAllowanceContext(t, node)
+
+	call, err := NewContractCall(ContractCallSpec{
+		Chain:    chain.Avalanche,
+		Contract: "USDC",
+		Abi:      "../../abi/Erc20.json",
+		Method:   "transfer",
+		Args: []ArgSpec{
+			{Kind: ArgContract, Value: chain.StargateRouter},
+			{Kind: ArgRandom, Min: "1.5", Max: "1.5", Token: "USDC"},
+		},
+		Approvals: []ApprovalSpec{{AmountSpec: AmountSpec{Amount: "2.5"}, Token: "USDC", Spender: chain.WooRouter}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := call.CanExecute(ac); err != nil || !ok {
+		t.Fatalf("CanExecute() = %v, %v, want true", ok, err)
+	}
+	if ok, err := call.Execute(ac); err != nil || !ok {
+		t.Fatalf("Execute() = %v, %v, want true", ok, err)
+	}
+
+	if got := node.allowances[allowanceKey{avalanche.Tokens["USDC"], avalanche.Contracts[chain.WooRouter]}]; got.Cmp(big.NewInt(2500000)) != 0 {
+		t.Errorf("allowance = %v, want 2.5 USDC in the minimal unit", got)
+	}
+	want, err := call.abi.Pack("transfer", avalanche.Contracts[chain.StargateRouter], big.NewInt(1500000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(node.sent) != 2 || !bytes.Equal(node.sent[1].Data(), want) {
+		t.Errorf("sent %d tx, want an approve then a transfer of 1.5 USDC", len(node.sent))
+	}
+
+	spec := call.Spec
+	spec.Args = []ArgSpec{{Kind: ArgContract, Value: chain.StargateRouter, Token: "USDC"}, spec.Args[1]}
+	if _, err := NewContractCall(spec); err == nil {
+		t.Error("NewContractCall() error = nil, want an error for a token bound contract argument")
+	}
+}
+
+func TestContractCallContractsIncludeSpenders(t *testing.T) {
+	avalanche, err := chain.DefaultRegistry().Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := vaultDepositSpec(t, "")
+	spec.Approvals = append(spec.Approvals, ApprovalSpec{AmountSpec: AmountSpec{Amount: "1"}, Token: "USDT", Spender: chain.TraderJoeRouter})
+	call, err := NewContractCall(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := call.Contracts(avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []common.Address{
+		common.HexToAddress(spec.Contract),
+		avalanche.Tokens["USDC"],
+		avalanche.Tokens["USDT"],
+		avalanche.Contracts[chain.TraderJoeRouter],
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Contracts() = %v, want %v", got, want)
+	}
+}
+
+func TestContractCallBuildsDeadlineOnExecute(t *testing.T) {
+	avalanche, err := chain.DefaultRegistry().Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := newFakeErc20Node(avalanche)
+	ac := newAllowanceContext(t, node)
+	spec := vaultDepositSpec(t, "")
+	spec.Method = "depositBefore"
+	spec.Args = []ArgSpec{
+		{Kind: ArgToken, Value: "USDC"},
+		{Kind: ArgConstant, Value: "100"},
+		{Kind: ArgDeadline, Value: "5m"},
+	}
+	spec.Approvals = []ApprovalSpec{{AmountSpec: AmountSpec{Amount: "0.0001"}, Token: "USDC"}}
+	call, err := NewContractCall(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := call.CanExecute(ac); err != nil || !ok {
+		t.Fatalf("CanExecute() = %v, %v, want true", ok, err)
+	}
+	if call.args[2] != nil {
+		t.Errorf("deadline = %v after CanExecute(), want it built on execute", call.args[2])
+	}
+	sending := time.Now()
+	if ok, err := call.Execute(ac); err != nil || !ok {
+		t.Fatalf("Execute() = %v, %v, want true", ok, err)
+	}
+	if len(node.sent) != 2 {
+		t.Fatalf("sent %d tx, want an approve then the deposit", len(node.sent))
+	}
+	args, err := call.method.Inputs.Unpack(node.sent[1].Data()[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deadline := args[2].(*big.Int).Int64(); deadline < sending.Add(5*time.Minute).Unix() {
+		t.Errorf("deadline = %d, want 5 minutes after sending at %d", deadline, sending.Unix())
+	}
+
+	deadlineArg := 2
+	spec.Balances = []BalanceSpec{{AmountSpec: AmountSpec{Arg: &deadlineArg}, Token: "USDC"}}
+	if _, err := NewContractCall(spec); err == nil {
+		t.Error("NewContractCall() error = nil, want an error for a deadline amount")
+	}
+	spec.Balances = nil
+	spec.Args[2].Value = "soon"
+	if _, err := NewContractCall(spec); err == nil {
+		t.Error("NewContractCall() error = nil, want an error for an invalid deadline duration")
+	}
+}
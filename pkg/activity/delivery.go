@@ -0,0 +1,95 @@
+package activity
+
+import (
+	"activity-bot/pkg/util"
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"log"
+	"math/big"
+	"time"
+)
+
+var (
+	transferEventId         = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	receiveFromChainEventId = crypto.Keccak256Hash([]byte("ReceiveFromChain(uint16,address,uint256)"))
+)
+
+// Delivery records a cross-chain transfer confirmed on its destination chain.
+type Delivery struct {
+	SourceTx      common.Hash
+	DestinationTx common.Hash
+	Latency       time.Duration // Time between sending the source tx and seeing the destination event
+}
+
+// DeliveryTracker follows a cross-chain transfer on the destination chain until the funds arrive.
+// The waiter must be connected to the destination chain and started.
+type DeliveryTracker struct {
+	Waiter   *util.Waiter
+	Contract common.Address // Contract emitting the delivery event on the destination chain
+	Timeout  time.Duration
+}
+
+func NewDeliveryTracker(waiter *util.Waiter, contract common.Address, timeout time.Duration) *DeliveryTracker {
+	return &DeliveryTracker{
+		Waiter:   waiter,
+		Contract: contract,
+		Timeout:  timeout,
+	}
+}
+
+// Checkpoint returns the destination head, it must be taken before sending the source tx so that the
+// delivery event cannot be emitted before the block the tracker starts scanning from.
+func (d *DeliveryTracker) Checkpoint(ctx context.Context) (*big.Int, error) {
+	head, err := d.Waiter.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get destination chain head: %w", err)
+	}
+	return new(big.Int).SetUint64(head), nil
+}
+
+// AwaitTransfer waits for an ERC-20 transfer of the tracked token from the sender to the recipient of at least
+// the minimum amount, so that an unrelated transfer to the recipient is not taken for the delivery. A zero
+// sender matches any sender and a nil minimum any amount.
+func (d *DeliveryTracker) AwaitTransfer(ctx context.Context, fromBlock *big.Int, sourceTx common.Hash, sentAt time.Time, from common.Address, recipient common.Address, minAmount *big.Int) (*Delivery, error) {
+	var senders []common.Hash
+	if from != (common.Address{}) {
+		senders = []common.Hash{common.BytesToHash(from.Bytes())}
+	}
+	topics := [][]common.Hash{{transferEventId}, senders, {common.BytesToHash(recipient.Bytes())}}
+	return d.await(ctx, fromBlock, sourceTx, sentAt, topics, func(l types.Log) bool {
+		return minAmount == nil || (len(l.Data) == 32 && new(big.Int).SetBytes(l.Data).Cmp(minAmount) >= 0)
+	})
+}
+
+// AwaitEvent waits for an event of the tracked contract matching the topics, the event id included.
+func (d *DeliveryTracker) AwaitEvent(ctx context.Context, fromBlock *big.Int, sourceTx common.Hash, sentAt time.Time, topics [][]common.Hash) (*Delivery, error) {
+	return d.await(ctx, fromBlock, sourceTx, sentAt, topics, func(types.Log) bool { return true })
+}
+
+func (d *DeliveryTracker) await(ctx context.Context, fromBlock *big.Int, sourceTx common.Hash, sentAt time.Time, topics [][]common.Hash, predicate func(types.Log) bool) (*Delivery, error) {
+	log.Printf("Waiting up to %v for delivery of %s on destination contract %s\n", d.Timeout, sourceTx.Hex(), d.Contract.Hex())
+	timeoutCtx, cancel := context.WithTimeout(ctx, d.Timeout)
+	defer cancel()
+
+	filter := ethereum.FilterQuery{
+		FromBlock: fromBlock,
+		Addresses: []common.Address{d.Contract},
+		Topics:    topics,
+	}
+	event, err := util.WaitForLogOrTimeout(filter, predicate, d.Waiter, timeoutCtx)
+	if err != nil {
+		return nil, fmt.Errorf("delivery of %s not confirmed on destination chain: %w", sourceTx.Hex(), err)
+	}
+
+	delivery := &Delivery{
+		SourceTx:      sourceTx,
+		DestinationTx: event.TxHash,
+		Latency:       time.Since(sentAt),
+	}
+	log.Printf("Delivery of %s confirmed by destination tx %s after %v\n", sourceTx.Hex(), event.TxHash.Hex(), delivery.Latency)
+	return delivery, nil
+}
@@ -0,0 +1,148 @@
+package activity
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"testing"
+	"time"
+)
+
+var (
+	destinationToken = common.HexToAddress("0xB97EF9Ef8734C71904D8002F8b6Bc66Dd9c48a6E")
+	destinationPool  = common.HexToAddress("0x1205f31718499dBf1fCa446663B532Ef87481fe1")
+	recipient        = common.HexToAddress("0x2297aEbD383787A160DD0d9F71508148769342E3")
+	otherSender      = common.HexToAddress("0x45A01E4e04F14f7A4a6702c74187c5F6222033cd")
+)
+
+// fakeLogs serves the head and the logs of a destination chain, filtered like a node does.
+type fakeLogs struct {
+	head uint64
+	logs []types.Log
+}
+
+type fakeLogFilter struct {
+	Address   []common.Address `json:"address"`
+	Topics    [][]common.Hash  `json:"topics"`
+	FromBlock hexutil.Uint64   `json:"fromBlock"`
+	ToBlock   hexutil.Uint64   `json:"toBlock"`
+}
+
+func (f *fakeLogs) GetBlockByNumber(number string, full bool) *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(f.head), Difficulty: big.NewInt(0)}
+}
+
+func (f *fakeLogs) GetLogs(filter fakeLogFilter) []types.Log {
+	logs := make([]types.Log, 0)
+	for _, l := range f.logs {
+		if l.BlockNumber < uint64(filter.FromBlock) || l.BlockNumber > uint64(filter.ToBlock) || !matchesFilter(l, filter) {
+			continue
+		}
+		logs = append(logs, l)
+	}
+	return logs
+}
+
+func matchesFilter(l types.Log, filter fakeLogFilter) bool {
+	addressMatches := len(filter.Address) == 0
+	for _, address := range filter.Address {
+		addressMatches = addressMatches || address == l.Address
+	}
+	if !addressMatches {
+		return false
+	}
+	for i, alternatives := range filter.Topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+		if i >= len(l.Topics) {
+			return false
+		}
+		topicMatches := false
+		for _, topic := range alternatives {
+			topicMatches = topicMatches || topic == l.Topics[i]
+		}
+		if !topicMatches {
+			return false
+		}
+	}
+	return true
+}
+
+func transferLog(block uint64, from, to common.Address, amount int64) types.Log {
+	return types.Log{
+		Address:     destinationToken,
+		Topics:      []common.Hash{transferEventId, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:        common.LeftPadBytes(big.NewInt(amount).Bytes(), 32),
+		BlockNumber: block,
+		TxHash:      common.BigToHash(big.NewInt(int64(block))),
+	}
+}
+
+func newDeliveryTracker(t *testing.T, f *fakeLogs, timeout time.Duration) *DeliveryTracker {
+	_, waiter := dialFakeNode(t, f)
+	return NewDeliveryTracker(waiter, destinationToken, timeout)
+}
+
+func TestAwaitTransfer(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    common.Address
+		logs    []types.Log
+		wantTx  common.Hash
+		wantErr bool
+	}{
+		{
+			name:   "transfer from the pool",
+			from:   destinationPool,
+			logs:   []types.Log{transferLog(12, destinationPool, recipient, 990)},
+			wantTx: common.BigToHash(big.NewInt(12)),
+		},
+		{
+			name:   "transfer from any sender",
+			logs:   []types.Log{transferLog(12, otherSender, recipient, 990)},
+			wantTx: common.BigToHash(big.NewInt(12)),
+		},
+		{
+			name: "skips transfers from another sender and below the minimum",
+			from: destinationPool,
+			logs: []types.Log{
+				transferLog(11, otherSender, recipient, 5000),
+				transferLog(12, destinationPool, recipient, 989),
+				transferLog(13, destinationPool, recipient, 1000),
+			},
+			wantTx: common.BigToHash(big.NewInt(13)),
+		},
+		{
+			name:    "transfer to another recipient",
+			from:    destinationPool,
+			logs:    []types.Log{transferLog(12, destinationPool, otherSender, 1000)},
+			wantErr: true,
+		},
+		{
+			name:    "times out without transfer",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newDeliveryTracker(t, &fakeLogs{head: 20, logs: tt.logs}, time.Second)
+			sourceTx := common.HexToHash("0x01")
+			delivery, err := tracker.AwaitTransfer(context.Background(), big.NewInt(10), sourceTx, time.Now(), tt.from, recipient, big.NewInt(990))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("AwaitTransfer() = %v, want an error", delivery.DestinationTx)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AwaitTransfer() error = %v", err)
+			}
+			if delivery.DestinationTx != tt.wantTx || delivery.SourceTx != sourceTx {
+				t.Errorf("AwaitTransfer() = %v from %v, want %v from %v", delivery.DestinationTx, delivery.SourceTx, tt.wantTx, sourceTx)
+			}
+		})
+	}
+}
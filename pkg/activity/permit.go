@@ -0,0 +1,107 @@
+package activity
+
+import (
+	"activity-bot/pkg/abi/erc20"
+	"activity-bot/pkg/abi/erc20Permit"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"log"
+	"math/big"
+	"time"
+)
+
+// permitTypeHash is the EIP-712 type hash of EIP-2612 permits.
+var permitTypeHash = crypto.Keccak256Hash([]byte("Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)"))
+
+// Permit is a signed EIP-2612 permit, passed to the permit-capable entry point of the spender in place of an
+// approve transaction.
+type Permit struct {
+	Owner    common.Address
+	Spender  common.Address
+	Value    *big.Int
+	Deadline *big.Int
+	V        uint8
+	R        [32]byte
+	S        [32]byte
+}
+
+// permitDigest returns the EIP-712 digest of a permit for the token's domain separator.
+func permitDigest(domainSeparator [32]byte, owner, spender common.Address, value, nonce, deadline *big.Int) common.Hash {
+	structHash := crypto.Keccak256Hash(
+		permitTypeHash.Bytes(),
+		common.LeftPadBytes(owner.Bytes(), 32),
+		common.LeftPadBytes(spender.Bytes(), 32),
+		common.LeftPadBytes(value.Bytes(), 32),
+		common.LeftPadBytes(nonce.Bytes(), 32),
+		common.LeftPadBytes(deadline.Bytes(), 32),
+	)
+	return crypto.Keccak256Hash([]byte("\x19\x01"), domainSeparator[:], structHash.Bytes())
+}
+
+// signPermit signs a permit of the amount to the spender, valid for the duration. It fails when the token does
+// not implement EIP-2612 or the context has no signer.
+func signPermit(ac ActivityContext, tokenAddress, spender common.Address, amount *big.Int, validity time.Duration) (*Permit, error) {
+	if ac.Signer == nil {
+		return nil, errors.New("the activity context has no signer for permits")
+	}
+	token, err := erc20Permit.NewErc20Permit(tokenAddress, ac.Client)
+	if err != nil {
+		return nil, err
+	}
+	domainSeparator, err := token.DOMAINSEPARATOR(ac.CallOpts())
+	if err != nil {
+		return nil, fmt.Errorf("token %s does not support permits: %w", tokenAddress.Hex(), err)
+	}
+	nonce, err := token.Nonces(ac.CallOpts(), ac.Account.Address)
+	if err != nil {
+		return nil, fmt.Errorf("token %s does not support permits: %w", tokenAddress.Hex(), err)
+	}
+
+	deadline := big.NewInt(time.Now().Add(validity).Unix())
+	digest := permitDigest(domainSeparator, ac.Account.Address, spender, amount, nonce, deadline)
+	signature, err := ac.Signer(*ac.Account, digest.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) != crypto.SignatureLength {
+		return nil, fmt.Errorf("the signer returned a %d byte signature, want %d", len(signature), crypto.SignatureLength)
+	}
+	permit := &Permit{
+		Owner:    ac.Account.Address,
+		Spender:  spender,
+		Value:    amount,
+		Deadline: deadline,
+		V:        signature[64] + 27,
+	}
+	copy(permit.R[:], signature[:32])
+	copy(permit.S[:], signature[32:64])
+	return permit, nil
+}
+
+// approveOrPermit makes sure the spender can spend the amount of the token. When the allowance is too low and
+// the spender has a permit-capable entry point, it signs a permit off-chain and returns it for that entry point,
+// otherwise or when the token does not support permits it falls back to an approve transaction and returns nil.
+func approveOrPermit(ac ActivityContext, tokenAddress, spender common.Address, amount *big.Int, spenderAcceptsPermit bool) (*Permit, error) {
+	token, err := erc20.NewErc20(tokenAddress, ac.Client)
+	if err != nil {
+		return nil, err
+	}
+	if spenderAcceptsPermit {
+		allowance, err := token.Allowance(ac.CallOpts(), ac.Account.Address, spender)
+		if err != nil {
+			return nil, err
+		}
+		if allowance.Cmp(amount) >= 0 {
+			return nil, nil
+		}
+		permit, err := signPermit(ac, tokenAddress, spender, amount, DefaultDeadline)
+		if err == nil {
+			log.Printf("[%s] signed a permit of %s for %s\n", ac.Account.Address.Hex(), amount.String(), spender.Hex())
+			return permit, nil
+		}
+		log.Printf("[%s] falling back to approve: %v\n", ac.Account.Address.Hex(), err)
+	}
+	return nil, ensureAllowance(ac, token, spender, amount)
+}
@@ -0,0 +1,137 @@
+package activity
+
+import (
+	"activity-bot/pkg/chain"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestPermitTypeHash(t *testing.T) {
+	want := common.HexToHash("0x6e71edae12b1b97f4d1f60370fef10105fa2faae0126114a169c64845d6126c9")
+	if permitTypeHash != want {
+		t.Errorf("permitTypeHash = %s, want %s", permitTypeHash.Hex(), want.Hex())
+	}
+}
+
+func TestPermitDigest(t *testing.T) {
+	// The domain separator of USDC on Ethereum (name "USD Coin", version "2", chain 1), the digest is the EIP-712
+	// hash of the permit as computed by go-ethereum's typed data signer.
+	domainSeparator := common.HexToHash("0x06c37168a7db5138defc7866392bb87a741f9b3d104deb5094588ce041cae335")
+	owner := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	spender := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	want := common.HexToHash("0x5d50fab304dbec46f631dd24a2c8ca5f6cbc9ef54e3305755ea6b7e8344e9994")
+
+	if got := permitDigest(domainSeparator, owner, spender, big.NewInt(1000000), big.NewInt(7), big.NewInt(1700000000)); got != want {
+		t.Errorf("permitDigest() = %s, want %s", got.Hex(), want.Hex())
+	}
+}
+
+// newPermitContext returns a context on a fake node where USDC supports permits, signing with a fresh key.
+func newPermitContext(t *testing.T) (ActivityContext, *fakeErc20Node, common.Hash) {
+	avalanche, err := chain.DefaultRegistry().Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domainSeparator := crypto.Keccak256Hash([]byte("USDC domain"))
+	node := newFakeErc20Node(avalanche)
+	node.domainSeparators = map[common.Address]common.Hash{avalanche.Tokens["USDC"]: domainSeparator}
+	node.permitNonce = 3
+	ac := newAllowanceContext(t, node)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ac.Account = &accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+	if ac.Transactor, err = bind.NewKeyedTransactorWithChainID(key, big.NewInt(node.chainId)); err != nil {
+		t.Fatal(err)
+	}
+	ac.Signer = func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	}
+	return ac, node, domainSeparator
+}
+
+func TestSignPermit(t *testing.T) {
+	ac, _, domainSeparator := newPermitContext(t)
+	usdc := ac.Chain.Tokens["USDC"]
+	spender := ac.Chain.Contracts[chain.WooRouter]
+	amount := big.NewInt(2500000)
+
+	before := time.Now()
+	permit, err := signPermit(ac, usdc, spender, amount, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if permit.Owner != ac.Account.Address || permit.Spender != spender || permit.Value.Cmp(amount) != 0 {
+		t.Errorf("permit = %+v, want owner %s, spender %s and value %s", permit, ac.Account.Address.Hex(), spender.Hex(), amount)
+	}
+	if deadline := permit.Deadline.Int64(); deadline < before.Add(time.Hour).Unix() || deadline > time.Now().Add(time.Hour).Unix() {
+		t.Errorf("permit.Deadline = %d, want an hour from now", deadline)
+	}
+	if permit.V != 27 && permit.V != 28 {
+		t.Fatalf("permit.V = %d, want 27 or 28", permit.V)
+	}
+
+	// The permit must recover to the owner on the digest of the node's nonce, as the token would check it.
+	digest := permitDigest(domainSeparator, permit.Owner, spender, amount, big.NewInt(3), permit.Deadline)
+	signature := append(append(permit.R[:], permit.S[:]...), permit.V-27)
+	recovered, err := crypto.SigToPub(digest.Bytes(), signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := crypto.PubkeyToAddress(*recovered); got != ac.Account.Address {
+		t.Errorf("the permit recovers to %s, want %s", got.Hex(), ac.Account.Address.Hex())
+	}
+}
+
+func TestSignPermitRejectsMalformedSignature(t *testing.T) {
+	ac, _, _ := newPermitContext(t)
+	ac.Signer = func(account accounts.Account, hash []byte) ([]byte, error) {
+		return make([]byte, 64), nil
+	}
+	if _, err := signPermit(ac, ac.Chain.Tokens["USDC"], ac.Chain.Contracts[chain.WooRouter], big.NewInt(1), time.Hour); err == nil {
+		t.Error("signPermit() with a 64 byte signature succeeded, want an error")
+	}
+}
+
+func TestApproveOrPermit(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		spender    string
+		amount     int64
+		wantPermit bool
+		wantSent   int
+	}{
+		{"permit supported", "USDC", chain.WooRouter, 2500000, true, 0},
+		{"permit not supported", "USDT", chain.TraderJoeRouter, 2500000, false, 1},
+		{"allowance sufficient", "USDC", chain.StargateRouter, 1000, false, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ac, node, _ := newPermitContext(t)
+			token := ac.Chain.Tokens[test.token]
+			spender := ac.Chain.Contracts[test.spender]
+
+			permit, err := approveOrPermit(ac, token, spender, big.NewInt(test.amount), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (permit != nil) != test.wantPermit {
+				t.Errorf("approveOrPermit() = %+v, want a permit: %v", permit, test.wantPermit)
+			}
+			if len(node.sent) != test.wantSent {
+				t.Errorf("sent %d transactions, want %d", len(node.sent), test.wantSent)
+			}
+			if test.wantSent > 0 && node.allowances[allowanceKey{token, spender}].Cmp(big.NewInt(test.amount)) < 0 {
+				t.Errorf("allowance = %s, want at least %d", node.allowances[allowanceKey{token, spender}], test.amount)
+			}
+		})
+	}
+}
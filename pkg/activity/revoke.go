@@ -0,0 +1,154 @@
+package activity
+
+import (
+	"activity-bot/pkg/abi/erc20"
+	"activity-bot/pkg/chain"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+	"sort"
+)
+
+// Allowance is the allowance of an account's token to a spender, named after the chain's address books.
+type Allowance struct {
+	Chain   string
+	Owner   common.Address
+	Token   string // Symbol in the chain's token book
+	Spender string // Name in the chain's contract book
+	Amount  *big.Int
+}
+
+// ListAllowances returns the non-zero allowances of the account's tokens of the token book to the contracts of
+// the contract book, sorted by token then spender. Empty filters select every token or spender.
+func ListAllowances(ac ActivityContext, tokens []string, spenders []string) ([]Allowance, error) {
+	if len(tokens) == 0 {
+		tokens = sortedKeys(ac.Chain.Tokens)
+	}
+	if len(spenders) == 0 {
+		spenders = sortedKeys(ac.Chain.Contracts)
+	}
+
+	allowances := make([]Allowance, 0)
+	for _, symbol := range tokens {
+		tokenAddress, err := ac.Chain.Token(symbol)
+		if err != nil {
+			return nil, err
+		}
+		token, err := erc20.NewErc20(tokenAddress, ac.Client)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range spenders {
+			spender, err := ac.Chain.Contract(name)
+			if err != nil {
+				return nil, err
+			}
+			amount, err := token.Allowance(ac.CallOpts(), ac.Account.Address, spender)
+			if err != nil {
+				return nil, fmt.Errorf("unable to get %s allowance to %s on %s: %w", symbol, name, ac.Chain.Name, err)
+			}
+			if amount.Sign() == 0 {
+				continue
+			}
+			allowances = append(allowances, Allowance{
+				Chain:   ac.Chain.Name,
+				Owner:   ac.Account.Address,
+				Token:   symbol,
+				Spender: name,
+				Amount:  amount,
+			})
+		}
+	}
+	return allowances, nil
+}
+
+func sortedKeys(book map[string]common.Address) []string {
+	keys := make([]string, 0, len(book))
+	for key := range book {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RevokeAllowances sets the non-zero allowances of the account to zero, for every token and spender of the
+// chain's address books unless filtered.
+type RevokeAllowances struct {
+	chain    string
+	Tokens   []string    // Optional, symbols of the tokens to revoke
+	Spenders []string    // Optional, contract book names of the spenders to revoke
+	Revoked  []Allowance // Set on execute, the allowances set to zero
+	pending  []Allowance // Computed on can execute
+}
+
+func NewRevokeAllowances(chain string) *RevokeAllowances {
+	return &RevokeAllowances{
+		chain: chain,
+	}
+}
+
+func (r *RevokeAllowances) Chain() string {
+	return r.chain
+}
+
+func (r *RevokeAllowances) Contracts(c *chain.Chain) ([]common.Address, error) {
+	tokens := r.Tokens
+	if len(tokens) == 0 {
+		tokens = sortedKeys(c.Tokens)
+	}
+	return addressBook(c, nil, tokens)
+}
+
+func (r *RevokeAllowances) CanExecute(ac ActivityContext) (bool, error) {
+	var err error
+	r.pending, err = ListAllowances(ac, r.Tokens, r.Spenders)
+	if err != nil {
+		return false, err
+	}
+	if len(r.pending) == 0 {
+		log.Printf("[%s] has no allowance to revoke on %s\n", ac.Account.Address.Hex(), ac.Chain.Name)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (r *RevokeAllowances) Execute(ac ActivityContext) (bool, error) {
+	ac.Transactor.Context = ac.Context
+	log.Printf("[%s] started revoking %d allowances on %s\n", ac.Account.Address.Hex(), len(r.pending), ac.Chain.Name)
+	r.Revoked = nil
+
+	for _, allowance := range r.pending {
+		tokenAddress, err := ac.Chain.Token(allowance.Token)
+		if err != nil {
+			return false, err
+		}
+		spender, err := ac.Chain.Contract(allowance.Spender)
+		if err != nil {
+			return false, err
+		}
+		token, err := erc20.NewErc20(tokenAddress, ac.Client)
+		if err != nil {
+			return false, err
+		}
+
+		ac.Transactor.Value = big.NewInt(0)
+		ac.Transactor.GasLimit = 0 // Estimated
+		tx, err := token.Approve(ac.Transactor, spender, big.NewInt(0))
+		if err != nil {
+			return false, err
+		}
+		log.Printf("Revoke of %s allowance to %s tx sent: %s", allowance.Token, allowance.Spender, tx.Hash().Hex())
+		receipt, err := ac.WaitForReceipt(tx)
+		if err != nil {
+			return false, err
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			return false, errors.New(fmt.Sprintf("Revoke of %s allowance to %s tx failed: %s", allowance.Token, allowance.Spender, receipt.TxHash.Hex()))
+		}
+		r.Revoked = append(r.Revoked, allowance)
+	}
+	return true, nil
+}
@@ -0,0 +1,248 @@
+package activity
+
+import (
+	"activity-bot/pkg/chain"
+	"bytes"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+var (
+	allowanceSelector       = crypto.Keccak256([]byte("allowance(address,address)"))[:4]
+	approveSelector         = crypto.Keccak256([]byte("approve(address,uint256)"))[:4]
+	decimalsSelector        = crypto.Keccak256([]byte("decimals()"))[:4]
+	domainSeparatorSelector = crypto.Keccak256([]byte("DOMAIN_SEPARATOR()"))[:4]
+	noncesSelector          = crypto.Keccak256([]byte("nonces(address)"))[:4]
+)
+
+type allowanceKey struct {
+	token   common.Address
+	spender common.Address
+}
+
+// fakeErc20Node answers the allowances of the account's tokens and mines the transactions it receives, approve
+// transactions set the allowance. Tokens with a domain separator support permits, the others revert.
+type fakeErc20Node struct {
+	lock             sync.Mutex
+	chainId          int64
+	decimals         map[common.Address]uint8
+	allowances       map[allowanceKey]*big.Int
+	domainSeparators map[common.Address]common.Hash
+	permitNonce      uint64
+	nonce            uint64
+	receipts         map[common.Hash]*types.Receipt
+	sent             []*types.Transaction
+}
+
+type fakeCallArgs struct {
+	To    *common.Address `json:"to"`
+	Data  hexutil.Bytes   `json:"data"`
+	Input hexutil.Bytes   `json:"input"`
+}
+
+func (f *fakeErc20Node) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(f.chainId))
+}
+
+func (f *fakeErc20Node) Call(args fakeCallArgs, block string) (hexutil.Bytes, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	data := args.Data
+	if len(data) == 0 {
+		data = args.Input
+	}
+	if len(data) == 4 && bytes.Equal(data, decimalsSelector) {
+		decimals, ok := f.decimals[*args.To]
+		if !ok {
+			return nil, errors.New("execution reverted")
+		}
+		return common.LeftPadBytes([]byte{decimals}, 32), nil
+	}
+	if len(data) == 4 && bytes.Equal(data, domainSeparatorSelector) {
+		domainSeparator, ok := f.domainSeparators[*args.To]
+		if !ok {
+			return nil, errors.New("execution reverted")
+		}
+		return domainSeparator.Bytes(), nil
+	}
+	if len(data) == 4+32 && bytes.Equal(data[:4], noncesSelector) {
+		return common.LeftPadBytes(new(big.Int).SetUint64(f.permitNonce).Bytes(), 32), nil
+	}
+	if len(data) != 4+2*32 || !bytes.Equal(data[:4], allowanceSelector) {
+		return nil, rpc.ErrNoResult
+	}
+	amount, ok := f.allowances[allowanceKey{*args.To, common.BytesToAddress(data[4+32:])}]
+	if !ok {
+		amount = big.NewInt(0)
+	}
+	return common.LeftPadBytes(amount.Bytes(), 32), nil
+}
+
+func (f *fakeErc20Node) GetCode(address common.Address, block string) hexutil.Bytes {
+	return hexutil.Bytes{0x60, 0x80}
+}
+
+func (f *fakeErc20Node) GasPrice() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(25000000000))
+}
+
+func (f *fakeErc20Node) EstimateGas(args fakeCallArgs) hexutil.Uint64 {
+	return 50000
+}
+
+func (f *fakeErc20Node) GetTransactionCount(address common.Address, block string) hexutil.Uint64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return hexutil.Uint64(f.nonce)
+}
+
+func (f *fakeErc20Node) GetBlockByNumber(number string, full bool) *types.Header {
+	return &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(0)}
+}
+
+func (f *fakeErc20Node) SendRawTransaction(encoded hexutil.Bytes) (common.Hash, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(encoded); err != nil {
+		return common.Hash{}, err
+	}
+	data := tx.Data()
+	if len(data) == 4+2*32 && bytes.Equal(data[:4], approveSelector) {
+		f.allowances[allowanceKey{*tx.To(), common.BytesToAddress(data[4:36])}] = new(big.Int).SetBytes(data[36:])
+	}
+	f.sent = append(f.sent, tx)
+	f.nonce++
+	f.receipts[tx.Hash()] = &types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		TxHash:      tx.Hash(),
+		BlockNumber: big.NewInt(1),
+		Logs:        []*types.Log{},
+	}
+	return tx.Hash(), nil
+}
+
+func (f *fakeErc20Node) GetTransactionReceipt(hash common.Hash) *types.Receipt {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.receipts[hash]
+}
+
+func newAllowanceContext(t *testing.T, f *fakeErc20Node) ActivityContext {
+	return newFakeNodeContext(t, f.chainId, f)
+}
+
+func newFakeErc20Node(c *chain.Chain) *fakeErc20Node {
+	return &fakeErc20Node{
+		chainId: int64(c.ChainId),
+		decimals: map[common.Address]uint8{
+			c.Tokens["USDC"]:  6,
+			c.Tokens["USDT"]:  6,
+			c.Tokens["BTC.b"]: 8,
+		},
+		allowances: map[allowanceKey]*big.Int{
+			{c.Tokens["USDC"], c.Contracts[chain.StargateRouter]}:  big.NewInt(1000),
+			{c.Tokens["USDC"], c.Contracts[chain.WooRouter]}:       big.NewInt(0),
+			{c.Tokens["BTC.b"], c.Contracts[chain.BitcoinBridge]}:  big.NewInt(5),
+			{c.Tokens["USDT"], c.Contracts[chain.TraderJoeRouter]}: big.NewInt(7),
+		},
+		receipts: make(map[common.Hash]*types.Receipt),
+	}
+}
+
+func allowanceNames(allowances []Allowance) []string {
+	names := make([]string, 0, len(allowances))
+	for _, allowance := range allowances {
+		names = append(names, allowance.Token+"/"+allowance.Spender+"/"+allowance.Amount.String())
+	}
+	return names
+}
+
+func TestListAllowances(t *testing.T) {
+	avalanche, err := chain.DefaultRegistry().Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		tokens   []string
+		spenders []string
+		want     []string
+	}{
+		{
+			name: "every token and spender",
+			want: []string{"BTC.b/bitcoinBridge/5", "USDC/stargateRouter/1000", "USDT/traderJoeRouter/7"},
+		},
+		{
+			name:   "filtered tokens",
+			tokens: []string{"USDC"},
+			want:   []string{"USDC/stargateRouter/1000"},
+		},
+		{
+			name:     "filtered spenders skipping zero allowances",
+			spenders: []string{chain.WooRouter, chain.TraderJoeRouter},
+			want:     []string{"USDT/traderJoeRouter/7"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac := newAllowanceContext(t, newFakeErc20Node(avalanche))
+			allowances, err := ListAllowances(ac, tt.tokens, tt.spenders)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := allowanceNames(allowances); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListAllowances() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	ac := newAllowanceContext(t, newFakeErc20Node(avalanche))
+	if _, err := ListAllowances(ac, []string{"DAI"}, nil); err == nil {
+		t.Error("ListAllowances() error = nil, want an error for a token outside the token book")
+	}
+}
+
+func TestRevokeAllowances(t *testing.T) {
+	avalanche, err := chain.DefaultRegistry().Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := newFakeErc20Node(avalanche)
+	ac := newAllowanceContext(t, node)
+
+	revoke := NewRevokeAllowances(chain.Avalanche)
+	revoke.Tokens = []string{"USDC", "BTC.b"}
+	ok, err := revoke.CanExecute(ac)
+	if err != nil || !ok {
+		t.Fatalf("CanExecute() = %v, %v, want true", ok, err)
+	}
+	ok, err = revoke.Execute(ac)
+	if err != nil || !ok {
+		t.Fatalf("Execute() = %v, %v, want true", ok, err)
+	}
+	if got, want := allowanceNames(revoke.Revoked), []string{"USDC/stargateRouter/1000", "BTC.b/bitcoinBridge/5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Revoked = %v, want %v", got, want)
+	}
+
+	// The revoked allowances are zero, the filtered out one is untouched
+	left, err := ListAllowances(ac, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := allowanceNames(left), []string{"USDT/traderJoeRouter/7"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("allowances after revoke = %v, want %v", got, want)
+	}
+	ok, err = revoke.CanExecute(ac)
+	if err != nil || ok {
+		t.Errorf("CanExecute() after revoke = %v, %v, want false without allowance left", ok, err)
+	}
+}
@@ -0,0 +1,84 @@
+package activity
+
+import (
+	"activity-bot/pkg/chain"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"log"
+)
+
+// Verify makes sure the activity context is connected to the chain the activity runs on: the context
+// chain must be the activity chain, the node must report its chain id and every contract the activity
+// uses must have code. It must pass before anything is signed for the activity.
+func Verify(ac ActivityContext, activity Activity) error {
+	if ac.Chain == nil {
+		return fmt.Errorf("activity runs on %s but the context has no chain", activity.Chain())
+	}
+	if ac.Chain.Name != activity.Chain() {
+		return fmt.Errorf("activity runs on %s but the context is on %s", activity.Chain(), ac.Chain.Name)
+	}
+
+	chainId, err := ac.Client.ChainID(ac.Context)
+	if err != nil {
+		return fmt.Errorf("unable to get chain id of %s: %w", ac.Chain.Name, err)
+	}
+	if !chainId.IsUint64() || chainId.Uint64() != ac.Chain.ChainId {
+		return fmt.Errorf("rpc endpoint of %s is on chain id %s, expected %d", ac.Chain.Name, chainId, ac.Chain.ChainId)
+	}
+
+	contractActivity, ok := activity.(ContractActivity)
+	if !ok {
+		return nil
+	}
+	contracts, err := contractActivity.Contracts(ac.Chain)
+	if err != nil {
+		return err
+	}
+	for _, contract := range contracts {
+		code, err := ac.Client.CodeAt(ac.Context, contract, nil)
+		if err != nil {
+			return fmt.Errorf("unable to get code of %s on %s: %w", contract.Hex(), ac.Chain.Name, err)
+		}
+		if len(code) == 0 {
+			return fmt.Errorf("no contract deployed at %s on %s", contract.Hex(), ac.Chain.Name)
+		}
+	}
+	return nil
+}
+
+// Run verifies the activity context then executes the activity if it can be.
+func Run(ac ActivityContext, activity Activity) (bool, error) {
+	if err := Verify(ac, activity); err != nil {
+		return false, err
+	}
+
+	canExecute, err := activity.CanExecute(ac)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("Can execute: %v", canExecute)
+	if !canExecute {
+		return false, nil
+	}
+	return activity.Execute(ac)
+}
+
+// addressBook resolves contract book names and token symbols of the chain.
+func addressBook(c *chain.Chain, contracts []string, tokens []string) ([]common.Address, error) {
+	addresses := make([]common.Address, 0, len(contracts)+len(tokens))
+	for _, name := range contracts {
+		address, err := c.Contract(name)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	for _, symbol := range tokens {
+		address, err := c.Token(symbol)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
@@ -0,0 +1,105 @@
+package activity
+
+import (
+	"activity-bot/pkg/chain"
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+var (
+	deployed    = common.HexToAddress("0x45A01E4e04F14f7A4a6702c74187c5F6222033cd")
+	notDeployed = common.HexToAddress("0xB97EF9Ef8734C71904D8002F8b6Bc66Dd9c48a6E")
+)
+
+type fakeEth struct {
+	chainId int64
+}
+
+func (f *fakeEth) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(f.chainId))
+}
+
+func (f *fakeEth) GetCode(address common.Address, block string) hexutil.Bytes {
+	if address == deployed {
+		return hexutil.Bytes{0x60, 0x80}
+	}
+	return hexutil.Bytes{}
+}
+
+type fakeActivity struct {
+	chain     string
+	contracts []common.Address
+}
+
+func (f *fakeActivity) Chain() string {
+	return f.chain
+}
+
+func (f *fakeActivity) Contracts(*chain.Chain) ([]common.Address, error) {
+	return f.contracts, nil
+}
+
+func (f *fakeActivity) CanExecute(ActivityContext) (bool, error) {
+	return true, nil
+}
+
+func (f *fakeActivity) Execute(ActivityContext) (bool, error) {
+	return true, nil
+}
+
+func newContext(t *testing.T, chainId int64) ActivityContext {
+	cl, _ := dialFakeNode(t, &fakeEth{chainId: chainId})
+	c, err := chain.DefaultRegistry().Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ActivityContext{Chain: c, Client: cl, Context: context.Background()}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		chainId  int64
+		activity *fakeActivity
+		wantErr  string
+	}{
+		{
+			name:     "matching chain with deployed contracts",
+			chainId:  43114,
+			activity: &fakeActivity{chain: chain.Avalanche, contracts: []common.Address{deployed}},
+		},
+		{
+			name:     "activity on another chain",
+			chainId:  43114,
+			activity: &fakeActivity{chain: chain.Fantom},
+			wantErr:  "activity runs on fantom but the context is on avalanche",
+		},
+		{
+			name:     "endpoint on another chain",
+			chainId:  250,
+			activity: &fakeActivity{chain: chain.Avalanche},
+			wantErr:  "is on chain id 250, expected 43114",
+		},
+		{
+			name:     "missing contract",
+			chainId:  43114,
+			activity: &fakeActivity{chain: chain.Avalanche, contracts: []common.Address{deployed, notDeployed}},
+			wantErr:  "no contract deployed at " + notDeployed.Hex(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(newContext(t, tt.chainId), tt.activity)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Verify() error = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package activity
+
+import (
+	"activity-bot/pkg/abi/erc20"
+	"activity-bot/pkg/abi/stargateFactory"
+	"activity-bot/pkg/abi/stargatePool"
+	"activity-bot/pkg/abi/stargateRouter"
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/random"
+	"activity-bot/pkg/token"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+)
+
+// StargateAddLiquidity deposits a token into its Stargate pool through the router, e.g. USDC into pool 1 on
+// Avalanche. The pool mints LP tokens to the account.
+type StargateAddLiquidity struct {
+	chain         string
+	Token         string   // Symbol of the pool token, e.g. USDC
+	MinAmount     string   // Decimal amount of the token, such as "12.5"
+	MaxAmount     string   // Decimal amount of the token
+	LPMinted      *big.Int // Set on execute, LP tokens received for the deposit
+	LPBalance     *big.Int // Set on execute, LP balance of the account after the deposit
+	router        *stargateRouter.StargateRouter
+	routerAddress common.Address
+	erc20         *erc20.Erc20
+	token         *token.Token
+	pool          *stargatePool.StargatePool
+	poolId        *big.Int // Computed on can execute
+	value         *big.Int // Computed on can execute
+}
+
+func NewStargateAddLiquidity(chain string, token string, minAmount string, maxAmount string) *StargateAddLiquidity {
+	return &StargateAddLiquidity{
+		chain:     chain,
+		Token:     token,
+		MinAmount: minAmount,
+		MaxAmount: maxAmount,
+	}
+}
+
+func (s *StargateAddLiquidity) Chain() string {
+	return s.chain
+}
+
+func (s *StargateAddLiquidity) Contracts(c *chain.Chain) ([]common.Address, error) {
+	return addressBook(c, []string{chain.StargateRouter}, []string{s.Token})
+}
+
+func (s *StargateAddLiquidity) CanExecute(ac ActivityContext) (bool, error) {
+	var err error
+	s.poolId, err = ac.Chain.StargatePool(s.Token)
+	if err != nil {
+		return false, err
+	}
+	s.router, s.routerAddress, s.pool, err = stargateRouterAndPool(ac, s.poolId)
+	if err != nil {
+		return false, err
+	}
+	s.token, err = token.Resolve(ac.CallOpts(), ac.Chain, ac.Client, s.Token)
+	if err != nil {
+		return false, err
+	}
+	valueSupplier, err := s.token.Supplier(s.MinAmount, s.MaxAmount)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("Creating %s contract instance on %s\n", s.Token, ac.Chain.Name)
+	s.erc20, err = erc20.NewErc20(s.token.Address, ac.Client)
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("Generating a random value to deposit between %s and %s %s\n", s.MinAmount, s.MaxAmount, s.Token)
+	balance, err := s.erc20.BalanceOf(ac.CallOpts(), ac.Account.Address)
+	if err != nil {
+		return false, err
+	}
+	if balance.Cmp(valueSupplier.Min()) < 0 {
+		return false, errors.New(fmt.Sprintf("Account [%s] has not enough %s balance to add liquidity", ac.Account.Address.Hex(), s.Token))
+	}
+	s.value, err = drawValue(ac, valueSupplier, balance)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *StargateAddLiquidity) Execute(ac ActivityContext) (bool, error) {
+	ac.Transactor.Context = ac.Context
+	log.Printf("[%s] started adding %s %s to Stargate pool %s on %s\n", ac.Account.Address.Hex(), s.token.Format(s.value), s.Token, s.poolId.String(), ac.Chain.Name)
+	s.LPMinted = nil
+	s.LPBalance = nil
+
+	log.Printf("Checking if %s allowance required\n", s.Token)
+	if err := ensureAllowance(ac, s.erc20, s.routerAddress, s.value); err != nil {
+		return false, err
+	}
+
+	lpBefore, err := s.pool.BalanceOf(ac.CallOpts(), ac.Account.Address)
+	if err != nil {
+		return false, err
+	}
+
+	ac.Transactor.Value = big.NewInt(0)
+	ac.Transactor.GasLimit = 300000
+	tx, err := s.router.AddLiquidity(ac.Transactor, s.poolId, s.value, ac.Account.Address)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("StargateRouter addLiquidity tx sent: %s", tx.Hash().Hex())
+	receipt, err := ac.WaitForReceipt(tx)
+	if err != nil {
+		return false, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return false, errors.New(fmt.Sprintf("StargateRouter addLiquidity tx failed: %s", receipt.TxHash.Hex()))
+	}
+
+	s.LPBalance, err = s.pool.BalanceOf(ac.CallOpts(), ac.Account.Address)
+	if err != nil {
+		return false, err
+	}
+	s.LPMinted = new(big.Int).Sub(s.LPBalance, lpBefore)
+	log.Printf("[%s] received %s LP of Stargate pool %s, LP balance: %s\n", ac.Account.Address.Hex(), s.LPMinted.String(), s.poolId.String(), s.LPBalance.String())
+	return true, nil
+}
+
+// StargateRedeemLocal withdraws a share of the account's LP tokens of a Stargate pool back to the pool token on
+// the same chain with instantRedeemLocal. The pool only redeems up to its delta credit and burns the LP tokens
+// it redeemed.
+type StargateRedeemLocal struct {
+	chain         string
+	Token         string           // Symbol of the pool token, e.g. USDC
+	ShareSupplier *random.Supplier // Share of the LP balance in basis points
+	LPBurned      *big.Int         // Set on execute, LP tokens burnt by the redeem
+	LPBalance     *big.Int         // Set on execute, LP balance of the account after the redeem
+	router        *stargateRouter.StargateRouter
+	pool          *stargatePool.StargatePool
+	poolId        *big.Int // Computed on can execute
+	amountLP      *big.Int // Computed on can execute
+}
+
+func NewStargateRedeemLocal(chain string, token string, shareSupplier *random.Supplier) *StargateRedeemLocal {
+	return &StargateRedeemLocal{
+		chain:         chain,
+		Token:         token,
+		ShareSupplier: shareSupplier,
+	}
+}
+
+func (s *StargateRedeemLocal) Chain() string {
+	return s.chain
+}
+
+func (s *StargateRedeemLocal) Contracts(c *chain.Chain) ([]common.Address, error) {
+	return addressBook(c, []string{chain.StargateRouter}, nil)
+}
+
+func (s *StargateRedeemLocal) CanExecute(ac ActivityContext) (bool, error) {
+	if s.ShareSupplier.Min().Sign() <= 0 || s.ShareSupplier.Max().Cmp(big.NewInt(10000)) > 0 {
+		return false, errors.New("StargateRedeemLocal share must be within (0, 10000] bps")
+	}
+	var err error
+	s.poolId, err = ac.Chain.StargatePool(s.Token)
+	if err != nil {
+		return false, err
+	}
+	if !s.poolId.IsUint64() || s.poolId.Uint64() > 0xffff {
+		return false, errors.New(fmt.Sprintf("Stargate pool %s can not be redeemed locally", s.poolId.String()))
+	}
+	s.router, _, s.pool, err = stargateRouterAndPool(ac, s.poolId)
+	if err != nil {
+		return false, err
+	}
+
+	lpBalance, err := s.pool.BalanceOf(ac.CallOpts(), ac.Account.Address)
+	if err != nil {
+		return false, err
+	}
+	if lpBalance.Sign() <= 0 {
+		return false, errors.New(fmt.Sprintf("Account [%s] has no LP of Stargate pool %s to redeem", ac.Account.Address.Hex(), s.poolId.String()))
+	}
+
+	deltaCredit, err := s.pool.DeltaCredit(ac.CallOpts())
+	if err != nil {
+		return false, err
+	}
+	if deltaCredit.Sign() == 0 {
+		log.Printf("[%s] skipping StargateRedeemLocal, Stargate pool %s has no credit for instant redeems\n", ac.Account.Address.Hex(), s.poolId.String())
+		return false, nil
+	}
+	totalLiquidity, err := s.pool.TotalLiquidity(ac.CallOpts())
+	if err != nil {
+		return false, err
+	}
+	totalSupply, err := s.pool.TotalSupply(ac.CallOpts())
+	if err != nil {
+		return false, err
+	}
+	var amountSD *big.Int
+	s.amountLP, amountSD = instantRedeemAmounts(lpBalance, s.ShareSupplier.Supply(), deltaCredit, totalLiquidity, totalSupply)
+	if s.amountLP.Sign() <= 0 {
+		return false, errors.New(fmt.Sprintf("Account [%s] has too little LP of Stargate pool %s to redeem", ac.Account.Address.Hex(), s.poolId.String()))
+	}
+	log.Printf("[%s] redeeming %s LP of Stargate pool %s for %s in shared decimals\n", ac.Account.Address.Hex(), s.amountLP.String(), s.poolId.String(), amountSD.String())
+
+	return true, nil
+}
+
+// instantRedeemAmounts returns the LP tokens to redeem for a share in basis points of the LP balance and what
+// they are worth in the pool's shared decimals. Like the pool, it caps the redeem to the delta credit, with
+// LP converted to shared decimals at the ratio of the total liquidity to the LP total supply.
+func instantRedeemAmounts(lpBalance *big.Int, shareBps *big.Int, deltaCredit *big.Int, totalLiquidity *big.Int, totalSupply *big.Int) (*big.Int, *big.Int) {
+	if totalSupply.Sign() == 0 || totalLiquidity.Sign() == 0 {
+		return big.NewInt(0), big.NewInt(0)
+	}
+	amountLP := new(big.Int).Mul(lpBalance, shareBps)
+	amountLP.Div(amountLP, big.NewInt(10000))
+	capLP := new(big.Int).Mul(deltaCredit, totalSupply)
+	capLP.Div(capLP, totalLiquidity)
+	if amountLP.Cmp(capLP) > 0 {
+		amountLP = capLP
+	}
+	amountSD := new(big.Int).Mul(amountLP, totalLiquidity)
+	return amountLP, amountSD.Div(amountSD, totalSupply)
+}
+
+func (s *StargateRedeemLocal) Execute(ac ActivityContext) (bool, error) {
+	ac.Transactor.Context = ac.Context
+	log.Printf("[%s] started redeeming %s LP of Stargate pool %s on %s\n", ac.Account.Address.Hex(), s.amountLP.String(), s.poolId.String(), ac.Chain.Name)
+	s.LPBurned = nil
+	s.LPBalance = nil
+
+	lpBefore, err := s.pool.BalanceOf(ac.CallOpts(), ac.Account.Address)
+	if err != nil {
+		return false, err
+	}
+
+	ac.Transactor.Value = big.NewInt(0)
+	ac.Transactor.GasLimit = 300000
+	tx, err := s.router.InstantRedeemLocal(ac.Transactor, uint16(s.poolId.Uint64()), s.amountLP, ac.Account.Address)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("StargateRouter instantRedeemLocal tx sent: %s", tx.Hash().Hex())
+	receipt, err := ac.WaitForReceipt(tx)
+	if err != nil {
+		return false, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return false, errors.New(fmt.Sprintf("StargateRouter instantRedeemLocal tx failed: %s", receipt.TxHash.Hex()))
+	}
+
+	s.LPBalance, err = s.pool.BalanceOf(ac.CallOpts(), ac.Account.Address)
+	if err != nil {
+		return false, err
+	}
+	s.LPBurned = new(big.Int).Sub(lpBefore, s.LPBalance)
+	log.Printf("[%s] redeemed %s LP of Stargate pool %s, LP balance: %s\n", ac.Account.Address.Hex(), s.LPBurned.String(), s.poolId.String(), s.LPBalance.String())
+	return true, nil
+}
+
+// stargateRouterAndPool returns the Stargate router of the chain and the pool the router's factory holds for
+// the pool id. The pool is also the LP token.
+func stargateRouterAndPool(ac ActivityContext, poolId *big.Int) (*stargateRouter.StargateRouter, common.Address, *stargatePool.StargatePool, error) {
+	routerAddress, err := ac.Chain.Contract(chain.StargateRouter)
+	if err != nil {
+		return nil, common.Address{}, nil, err
+	}
+	log.Printf("Creating StargateRouter contract instance on %s\n", ac.Chain.Name)
+	router, err := stargateRouter.NewStargateRouter(routerAddress, ac.Client)
+	if err != nil {
+		return nil, common.Address{}, nil, err
+	}
+
+	poolAddress, err := stargatePoolAddress(ac, router, poolId)
+	if err != nil {
+		return nil, common.Address{}, nil, err
+	}
+	log.Printf("Creating Stargate pool %s contract instance on %s\n", poolId.String(), ac.Chain.Name)
+	pool, err := stargatePool.NewStargatePool(poolAddress, ac.Client)
+	if err != nil {
+		return nil, common.Address{}, nil, err
+	}
+	return router, routerAddress, pool, nil
+}
+
+// stargatePoolAddress returns the address of the pool the router's factory holds for the pool id.
+func stargatePoolAddress(ac ActivityContext, router *stargateRouter.StargateRouter, poolId *big.Int) (common.Address, error) {
+	factoryAddress, err := router.Factory(ac.CallOpts())
+	if err != nil {
+		return common.Address{}, err
+	}
+	factory, err := stargateFactory.NewStargateFactory(factoryAddress, ac.Client)
+	if err != nil {
+		return common.Address{}, err
+	}
+	poolAddress, err := factory.GetPool(ac.CallOpts(), poolId)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if poolAddress == (common.Address{}) {
+		return common.Address{}, errors.New(fmt.Sprintf("Stargate pool %s does not exist on %s", poolId.String(), ac.Chain.Name))
+	}
+	return poolAddress, nil
+}
@@ -0,0 +1,238 @@
+package activity
+
+import (
+	"activity-bot/pkg/abi/stargateFactory"
+	"activity-bot/pkg/abi/stargatePool"
+	"activity-bot/pkg/abi/stargateRouter"
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/random"
+	"bytes"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"testing"
+)
+
+var (
+	fakeStargateFactory = common.HexToAddress("0x808d7c71ad2ba3FA531b068a2417C63106BC0949")
+	fakeStargatePool    = common.HexToAddress("0x1205f31718499dBf1fCa446663B532Ef87481fe1")
+)
+
+// fakeStargateNode adds the Stargate router, factory and USDC pool to a fake ERC-20 node. The router mints one
+// LP per USDC unit added, the LP balances are the ones of the pool.
+type fakeStargateNode struct {
+	*fakeErc20Node
+	router         common.Address
+	usdc           common.Address
+	usdcBalances   map[common.Address]*big.Int
+	lpBalances     map[common.Address]*big.Int
+	deltaCredit    *big.Int
+	totalLiquidity *big.Int
+	routerAbi      *abi.ABI
+	factoryAbi     *abi.ABI
+	poolAbi        *abi.ABI
+}
+
+func newFakeStargateNode(t *testing.T, c *chain.Chain) *fakeStargateNode {
+	routerAbi, err := stargateRouter.StargateRouterMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	factoryAbi, err := stargateFactory.StargateFactoryMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	poolAbi, err := stargatePool.StargatePoolMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeStargateNode{
+		fakeErc20Node:  newFakeErc20Node(c),
+		router:         c.Contracts[chain.StargateRouter],
+		usdc:           c.Tokens["USDC"],
+		usdcBalances:   make(map[common.Address]*big.Int),
+		lpBalances:     make(map[common.Address]*big.Int),
+		deltaCredit:    big.NewInt(0),
+		totalLiquidity: big.NewInt(0),
+		routerAbi:      routerAbi,
+		factoryAbi:     factoryAbi,
+		poolAbi:        poolAbi,
+	}
+}
+
+func (f *fakeStargateNode) Call(args fakeCallArgs, block string) (hexutil.Bytes, error) {
+	data := args.Data
+	if len(data) == 0 {
+		data = args.Input
+	}
+	if len(data) >= 4 && args.To != nil {
+		if result, ok, err := f.stargateCall(*args.To, data); ok {
+			return result, err
+		}
+	}
+	return f.fakeErc20Node.Call(args, block)
+}
+
+// stargateCall answers the calls to the Stargate contracts and the USDC balances, ok is false for other calls.
+func (f *fakeStargateNode) stargateCall(to common.Address, data []byte) (result hexutil.Bytes, ok bool, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	var method *abi.Method
+	var outputs []interface{}
+	switch to {
+	case f.router:
+		if method, err = f.routerAbi.MethodById(data[:4]); err == nil && method.Name == "factory" {
+			outputs = []interface{}{fakeStargateFactory}
+		}
+	case fakeStargateFactory:
+		if method, err = f.factoryAbi.MethodById(data[:4]); err == nil && method.Name == "getPool" {
+			outputs = []interface{}{fakeStargatePool}
+		}
+	case fakeStargatePool:
+		if method, err = f.poolAbi.MethodById(data[:4]); err != nil {
+			break
+		}
+		switch method.Name {
+		case "balanceOf":
+			outputs = []interface{}{balanceOf(f.lpBalances, common.BytesToAddress(data[4:]))}
+		case "deltaCredit":
+			outputs = []interface{}{f.deltaCredit}
+		case "totalLiquidity":
+			outputs = []interface{}{f.totalLiquidity}
+		case "totalSupply":
+			supply := big.NewInt(0)
+			for _, balance := range f.lpBalances {
+				supply.Add(supply, balance)
+			}
+			outputs = []interface{}{supply}
+		}
+	case f.usdc:
+		// The pool is an ERC-20 as well, its ABI packs the USDC balances
+		if method, err = f.poolAbi.MethodById(data[:4]); err == nil && method.Name == "balanceOf" {
+			outputs = []interface{}{balanceOf(f.usdcBalances, common.BytesToAddress(data[4:]))}
+		}
+	}
+	if outputs == nil {
+		return nil, false, nil
+	}
+	result, err = method.Outputs.Pack(outputs...)
+	return result, true, err
+}
+
+func (f *fakeStargateNode) SendRawTransaction(encoded hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(encoded); err != nil {
+		return common.Hash{}, err
+	}
+	addLiquidity := f.routerAbi.Methods["addLiquidity"]
+	if data := tx.Data(); *tx.To() == f.router && len(data) >= 4 && bytes.Equal(data[:4], addLiquidity.ID) {
+		args, err := addLiquidity.Inputs.Unpack(data[4:])
+		if err != nil {
+			return common.Hash{}, err
+		}
+		amount, to := args[1].(*big.Int), args[2].(common.Address)
+		f.lock.Lock()
+		f.lpBalances[to] = new(big.Int).Add(balanceOf(f.lpBalances, to), amount)
+		f.totalLiquidity.Add(f.totalLiquidity, amount)
+		f.lock.Unlock()
+	}
+	return f.fakeErc20Node.SendRawTransaction(encoded)
+}
+
+func balanceOf(balances map[common.Address]*big.Int, owner common.Address) *big.Int {
+	if balance, ok := balances[owner]; ok {
+		return balance
+	}
+	return big.NewInt(0)
+}
+
+func TestInstantRedeemAmounts(t *testing.T) {
+	tests := []struct {
+		name                             string
+		lpBalance, shareBps, deltaCredit int64
+		totalLiquidity, totalSupply      int64
+		wantLP, wantSD                   int64
+	}{
+		{"share of the balance", 1000, 5000, 1000000, 2000000, 1000000, 500, 1000},
+		{"whole balance", 1000, 10000, 1000000, 1000000, 1000000, 1000, 1000},
+		{"capped to the delta credit", 1000, 10000, 300, 2000000, 1000000, 150, 300},
+		{"rounds down", 3, 5000, 1000000, 1000000, 1000000, 1, 1},
+		{"empty pool", 1000, 10000, 1000, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amountLP, amountSD := instantRedeemAmounts(big.NewInt(tt.lpBalance), big.NewInt(tt.shareBps), big.NewInt(tt.deltaCredit), big.NewInt(tt.totalLiquidity), big.NewInt(tt.totalSupply))
+			if amountLP.Int64() != tt.wantLP || amountSD.Int64() != tt.wantSD {
+				t.Errorf("instantRedeemAmounts() = %v LP, %v SD, want %d LP, %d SD", amountLP, amountSD, tt.wantLP, tt.wantSD)
+			}
+		})
+	}
+}
+
+func TestStargateAddLiquidity(t *testing.T) {
+	avalanche, err := chain.DefaultRegistry().Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := newFakeStargateNode(t, avalanche)
+	ac := newFakeNodeContext(t, node.chainId, node)
+	node.usdcBalances[ac.Account.Address] = big.NewInt(10000000)
+	node.lpBalances[ac.Account.Address] = big.NewInt(4000)
+
+	activity := NewStargateAddLiquidity(chain.Avalanche, "USDC", "2.5", "2.5")
+	if ok, err := activity.CanExecute(ac); err != nil || !ok {
+		t.Fatalf("CanExecute() = %v, %v, want true", ok, err)
+	}
+	if ok, err := activity.Execute(ac); err != nil || !ok {
+		t.Fatalf("Execute() = %v, %v, want true", ok, err)
+	}
+
+	if activity.LPMinted.Cmp(big.NewInt(2500000)) != 0 || activity.LPBalance.Cmp(big.NewInt(2504000)) != 0 {
+		t.Errorf("LPMinted = %v, LPBalance = %v, want 2500000 minted for a balance of 2504000", activity.LPMinted, activity.LPBalance)
+	}
+	if len(node.sent) != 2 || !bytes.Equal(node.sent[0].Data()[:4], approveSelector) || *node.sent[1].To() != node.router {
+		t.Fatalf("sent %d tx, want an approve then addLiquidity on the router", len(node.sent))
+	}
+	if allowance := node.allowances[allowanceKey{node.usdc, node.router}]; allowance.Cmp(big.NewInt(2500000)) < 0 {
+		t.Errorf("router allowance = %v, want at least the 2.5 USDC added", allowance)
+	}
+}
+
+func TestStargateRedeemLocalCanExecute(t *testing.T) {
+	avalanche, err := chain.DefaultRegistry().Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		deltaCredit int64
+		want        bool
+		wantLP      int64
+	}{
+		{"no credit skips the redeem", 0, false, 0},
+		{"redeems up to the credit", 1000, true, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := newFakeStargateNode(t, avalanche)
+			ac := newFakeNodeContext(t, node.chainId, node)
+			node.lpBalances[ac.Account.Address] = big.NewInt(4000)
+			node.totalLiquidity = big.NewInt(4000)
+			node.deltaCredit = big.NewInt(tt.deltaCredit)
+
+			activity := NewStargateRedeemLocal(chain.Avalanche, "USDC", random.NewRangeSupplier(big.NewInt(10000), big.NewInt(10000)))
+			ok, err := activity.CanExecute(ac)
+			if err != nil || ok != tt.want {
+				t.Fatalf("CanExecute() = %v, %v, want %v", ok, err, tt.want)
+			}
+			if tt.want && activity.amountLP.Int64() != tt.wantLP {
+				t.Errorf("amountLP = %v, want %d", activity.amountLP, tt.wantLP)
+			}
+			if len(node.sent) != 0 {
+				t.Errorf("sent %d tx, want none", len(node.sent))
+			}
+		})
+	}
+}
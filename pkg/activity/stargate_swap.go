@@ -0,0 +1,321 @@
+package activity
+
+import (
+	"activity-bot/pkg/abi/erc20"
+	"activity-bot/pkg/abi/stargateRouter"
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/layerzero"
+	"activity-bot/pkg/random"
+	"activity-bot/pkg/token"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+	"time"
+)
+
+// stargateSwapFunction is the Stargate router function type quoted for a swap.
+const stargateSwapFunction = 1
+
+// StargateSwap bridges a token through the Stargate router between two chains of the registry, the pools are
+// resolved from the chains' Stargate pools so any pair of supported chains and tokens works.
+type StargateSwap struct {
+	FromChain       string
+	ToChain         string
+	FromToken       string        // Symbol of the source pool token, e.g. USDC
+	ToToken         string        // Symbol of the destination pool token, e.g. USDT
+	SlippageBps     uint64        // Accepted difference between the sent and the received amount
+	DstGas          uint64        // Extra gas for a call on the destination chain, none for plain swaps
+	MinAmount       string        // Decimal amount of the token, such as "12.5"
+	MaxAmount       string        // Decimal amount of the token
+	DeliveryTimeout time.Duration // Optional, waits for the funds on the destination chain when set
+	Delivery        *Delivery     // Set on execute once the delivery is confirmed
+	MinAirdrop      string        // Optional, decimal native amount delivered to the account on the destination chain
+	MaxAirdrop      string        // Decimal native amount of the destination chain
+	MaxAirdropCost  string        // Optional, decimal native amount, the airdrop is dropped when it adds more to the fee
+	router          *stargateRouter.StargateRouter
+	routerAddress   common.Address
+	erc20           *erc20.Erc20
+	token           *token.Token
+	fromPool        *big.Int         // Computed on can execute
+	toPool          *big.Int         // Computed on can execute
+	toChainId       uint16           // Computed on can execute
+	value           *big.Int         // Computed on can execute
+	airdrop         *random.Supplier // Computed on can execute
+	maxAirdropCost  *big.Int         // Computed on can execute
+}
+
+func NewStargateSwap(fromChain string, toChain string, fromToken string, toToken string, minAmount string, maxAmount string) *StargateSwap {
+	return &StargateSwap{
+		FromChain:   fromChain,
+		ToChain:     toChain,
+		FromToken:   fromToken,
+		ToToken:     toToken,
+		SlippageBps: DefaultBridgeSlippageBps,
+		MinAmount:   minAmount,
+		MaxAmount:   maxAmount,
+	}
+}
+
+func (s *StargateSwap) Chain() string {
+	return s.FromChain
+}
+
+func (s *StargateSwap) Contracts(c *chain.Chain) ([]common.Address, error) {
+	return addressBook(c, []string{chain.StargateRouter}, []string{s.FromToken})
+}
+
+func (s *StargateSwap) CanExecute(ac ActivityContext) (bool, error) {
+	if ac.Chains == nil {
+		return false, errors.New(fmt.Sprintf("StargateSwap needs the registry to resolve chain %s", s.ToChain))
+	}
+	destination, err := ac.Chains.Registry().Get(s.ToChain)
+	if err != nil {
+		return false, err
+	}
+	if destination.LayerZeroChainId == ac.Chain.LayerZeroChainId {
+		return false, errors.New(fmt.Sprintf("StargateSwap needs different chains, got %s twice", s.ToChain))
+	}
+	s.fromPool, err = ac.Chain.StargatePool(s.FromToken)
+	if err != nil {
+		return false, err
+	}
+	s.toPool, err = destination.StargatePool(s.ToToken)
+	if err != nil {
+		return false, err
+	}
+	s.toChainId = destination.LayerZeroChainId
+
+	if err := s.resolveAirdrop(ac, destination); err != nil {
+		return false, err
+	}
+
+	routerAddress, err := ac.Chain.Contract(chain.StargateRouter)
+	if err != nil {
+		return false, err
+	}
+	s.token, err = token.Resolve(ac.CallOpts(), ac.Chain, ac.Client, s.FromToken)
+	if err != nil {
+		return false, err
+	}
+	if s.token.IsNative() {
+		return false, errors.New(fmt.Sprintf("StargateSwap swaps ERC-20 tokens, %s is native", s.FromToken))
+	}
+	valueSupplier, err := s.token.Supplier(s.MinAmount, s.MaxAmount)
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("Creating StargateRouter contract instance on %s\n", ac.Chain.Name)
+	router, err := stargateRouter.NewStargateRouter(routerAddress, ac.Client)
+	if err != nil {
+		return false, err
+	}
+	s.router = router
+	s.routerAddress = routerAddress
+
+	log.Printf("Creating %s contract instance on %s\n", s.FromToken, ac.Chain.Name)
+	s.erc20, err = erc20.NewErc20(s.token.Address, ac.Client)
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("Generating a random value to swap between %s and %s %s\n", s.MinAmount, s.MaxAmount, s.FromToken)
+	balance, err := s.erc20.BalanceOf(ac.CallOpts(), ac.Account.Address)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Error getting account %s balance [%s]: %v", s.FromToken, ac.Account.Address.Hex(), err))
+	}
+	s.value, err = drawValue(ac, valueSupplier, balance)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// resolveAirdrop converts the airdrop bounds in the destination native token and its maximum cost in the source
+// native token.
+func (s *StargateSwap) resolveAirdrop(ac ActivityContext, destination *chain.Chain) error {
+	s.airdrop, s.maxAirdropCost = nil, nil
+	if s.MinAirdrop == "" && s.MaxAirdrop == "" {
+		return nil
+	}
+	var err error
+	s.airdrop, err = token.Native(destination).Supplier(s.MinAirdrop, s.MaxAirdrop)
+	if err != nil {
+		return err
+	}
+	if s.MaxAirdropCost != "" {
+		s.maxAirdropCost, err = token.Native(ac.Chain).Parse(s.MaxAirdropCost)
+	}
+	return err
+}
+
+func (s *StargateSwap) Execute(ac ActivityContext) (bool, error) {
+	ac.Transactor.Context = ac.Context
+	log.Printf("[%s] started cross swapping %s %s from %s to %s on %s using Stargate\n", ac.Account.Address.Hex(), s.token.Format(s.value), s.FromToken, ac.Chain.Name, s.ToToken, s.ToChain)
+	s.Delivery = nil
+
+	lzTxObj, err := stargateLzTxObj(layerzero.NewAdapterParams(s.DstGas))
+	if err != nil {
+		return false, err
+	}
+	fees, err := quoteStargateFee(ac, s.router, s.toChainId, lzTxObj)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("[%s] LZ Quote Fees: %v\n", ac.Account.Address, fees)
+	if s.airdrop != nil {
+		lzTxObj, fees, err = s.withAirdrop(ac, fees)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// The Stargate router has no permit entry point, even for permit tokens such as USDC the allowance is approved
+	log.Printf("Checking if %s allowance required\n", s.FromToken)
+	if _, err := approveOrPermit(ac, s.token.Address, s.routerAddress, s.value, false); err != nil {
+		return false, err
+	}
+
+	// Bridge
+	minAmount := applySlippage(s.value, s.SlippageBps)
+
+	var tracker *DeliveryTracker
+	var destinationFromBlock *big.Int
+	var destinationPool common.Address
+	var destinationMinAmount *big.Int
+	if s.DeliveryTimeout > 0 {
+		destination, err := ac.OnChain(s.ToChain)
+		if err != nil {
+			return false, err
+		}
+		destinationToken, err := token.Resolve(destination.CallOpts(), destination.Chain, destination.Client, s.ToToken)
+		if err != nil {
+			return false, err
+		}
+		// The pool pays the swap out, the amount is in the destination token decimals
+		destinationMinAmount = token.ConvertUnits(minAmount, s.token.Decimals, destinationToken.Decimals)
+		destinationRouterAddress, err := destination.Chain.Contract(chain.StargateRouter)
+		if err != nil {
+			return false, err
+		}
+		destinationRouter, err := stargateRouter.NewStargateRouter(destinationRouterAddress, destination.Client)
+		if err != nil {
+			return false, err
+		}
+		destinationPool, err = stargatePoolAddress(destination, destinationRouter, s.toPool)
+		if err != nil {
+			return false, err
+		}
+		tracker = NewDeliveryTracker(destination.Waiter, destinationToken.Address, s.DeliveryTimeout)
+		destinationFromBlock, err = tracker.Checkpoint(ac.Context)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	ac.Transactor.Value = fees
+	ac.Transactor.GasLimit = 600000
+	sentAt := time.Now()
+	tx, err := s.router.Swap(
+		ac.Transactor,
+		s.toChainId,
+		s.fromPool, // https://stargateprotocol.gitbook.io/stargate/developers/pool-ids
+		s.toPool,   // https://stargateprotocol.gitbook.io/stargate/developers/pool-ids
+		ac.Account.Address,
+		s.value,
+		minAmount,
+		lzTxObj,
+		ac.Account.Address.Bytes(),
+		make([]byte, 0),
+	)
+	ac.Transactor.Value = big.NewInt(0)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("StargateRouter cross swap tx sent: %s", tx.Hash().Hex())
+	receipt, err := ac.WaitForReceipt(tx)
+	if err != nil {
+		return false, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return false, errors.New(fmt.Sprintf("StargateRouter cross swap tx failed: %s", receipt.TxHash.Hex()))
+	}
+	log.Printf("StargateRouter cross swap tx confirmed: %s", receipt.TxHash.Hex())
+
+	if tracker != nil {
+		delivery, err := tracker.AwaitTransfer(ac.Context, destinationFromBlock, tx.Hash(), sentAt, destinationPool, ac.Account.Address, destinationMinAmount)
+		if err != nil {
+			return false, err
+		}
+		s.Delivery = delivery
+	}
+	return true, nil
+}
+
+// withAirdrop quotes the swap with a random destination native amount for the account, it returns the
+// parameters without airdrop when the airdrop costs more than the maximum.
+func (s *StargateSwap) withAirdrop(ac ActivityContext, fees *big.Int) (stargateRouter.IStargateRouterlzTxObj, *big.Int, error) {
+	amount := s.airdrop.Supply()
+	withoutAirdrop, err := stargateLzTxObj(layerzero.NewAdapterParams(s.DstGas))
+	if err != nil {
+		return withoutAirdrop, nil, err
+	}
+	lzTxObj, err := stargateLzTxObj(layerzero.NewAirdropAdapterParams(s.DstGas, amount, ac.Account.Address))
+	if err != nil {
+		return withoutAirdrop, nil, err
+	}
+	airdropFees, err := quoteStargateFee(ac, s.router, s.toChainId, lzTxObj)
+	if err != nil {
+		return withoutAirdrop, nil, err
+	}
+	cost, affordable := airdropCost(fees, airdropFees, s.maxAirdropCost)
+	if !affordable {
+		log.Printf("[%s] dropping airdrop of %s on %s, it costs %s above the maximum of %s\n", ac.Account.Address.Hex(), amount.String(), s.ToChain, cost.String(), s.maxAirdropCost.String())
+		return withoutAirdrop, fees, nil
+	}
+	log.Printf("[%s] airdropping %s on %s for %s, LZ Quote Fees: %v\n", ac.Account.Address.Hex(), amount.String(), s.ToChain, cost.String(), airdropFees)
+	return lzTxObj, airdropFees, nil
+}
+
+// airdropCost returns what an airdrop adds to the fee and whether it stays within the maximum, any cost is
+// accepted without maximum.
+func airdropCost(fees *big.Int, airdropFees *big.Int, maxCost *big.Int) (*big.Int, bool) {
+	cost := new(big.Int).Sub(airdropFees, fees)
+	return cost, maxCost == nil || cost.Cmp(maxCost) <= 0
+}
+
+// quoteStargateFee returns the LayerZero fee in native token for a swap of the account to the LayerZero chain.
+func quoteStargateFee(ac ActivityContext, router *stargateRouter.StargateRouter, toChainId uint16, lzTxObj stargateRouter.IStargateRouterlzTxObj) (*big.Int, error) {
+	fees, _, err := router.QuoteLayerZeroFee(
+		ac.CallOpts(),
+		toChainId,
+		stargateSwapFunction,
+		ac.Account.Address.Bytes(),
+		make([]byte, 0),
+		lzTxObj)
+	if err != nil {
+		return nil, err
+	}
+	return fees, nil
+}
+
+// stargateLzTxObj converts LayerZero adapter params to the Stargate router parameters, the router encodes
+// the adapter params itself.
+func stargateLzTxObj(params layerzero.AdapterParams) (stargateRouter.IStargateRouterlzTxObj, error) {
+	lzTxObj := stargateRouter.IStargateRouterlzTxObj{
+		DstGasForCall:   new(big.Int).SetUint64(params.DstGas),
+		DstNativeAmount: params.Airdrop(),
+		DstNativeAddr:   make([]byte, 0),
+	}
+	if err := params.Validate(); err != nil {
+		return lzTxObj, err
+	}
+	if params.Version == layerzero.AdapterParamsV2 {
+		lzTxObj.DstNativeAddr = params.NativeAddress.Bytes()
+	}
+	return lzTxObj, nil
+}
@@ -0,0 +1,177 @@
+package activity
+
+import (
+	"activity-bot/pkg/abi/stargateRouter"
+	"activity-bot/pkg/abi/stargateRouterEth"
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/layerzero"
+	"activity-bot/pkg/token"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+)
+
+const stargateSwapEthGasLimit = 600000
+
+// StargateSwapETH bridges native ETH through the Stargate RouterETH between chains with an ETH pool, such as
+// Ethereum, Arbitrum and Optimism.
+type StargateSwapETH struct {
+	FromChain   string
+	ToChain     string
+	SlippageBps uint64 // Accepted difference between the sent and the received amount
+	MinAmount   string // Decimal amount of ETH, such as "0.05"
+	MaxAmount   string // Decimal amount of ETH
+	router      *stargateRouter.StargateRouter
+	routerEth   *stargateRouterEth.StargateRouterEth
+	eth         *token.Token
+	toChainId   uint16   // Computed on can execute
+	value       *big.Int // Computed on can execute
+}
+
+func NewStargateSwapETH(fromChain string, toChain string, minAmount string, maxAmount string) *StargateSwapETH {
+	return &StargateSwapETH{
+		FromChain:   fromChain,
+		ToChain:     toChain,
+		SlippageBps: DefaultBridgeSlippageBps,
+		MinAmount:   minAmount,
+		MaxAmount:   maxAmount,
+	}
+}
+
+func (s *StargateSwapETH) Chain() string {
+	return s.FromChain
+}
+
+func (s *StargateSwapETH) Contracts(c *chain.Chain) ([]common.Address, error) {
+	return addressBook(c, []string{chain.StargateRouter, chain.StargateRouterEth}, nil)
+}
+
+func (s *StargateSwapETH) CanExecute(ac ActivityContext) (bool, error) {
+	if ac.Chains == nil {
+		return false, errors.New(fmt.Sprintf("StargateSwapETH needs the registry to resolve chain %s", s.ToChain))
+	}
+	destination, err := ac.Chains.Registry().Get(s.ToChain)
+	if err != nil {
+		return false, err
+	}
+	if destination.LayerZeroChainId == ac.Chain.LayerZeroChainId {
+		return false, errors.New(fmt.Sprintf("StargateSwapETH needs different chains, got %s twice", s.ToChain))
+	}
+	// Both sides need an ETH pool, the router picks them itself
+	if _, err := ac.Chain.StargatePool("ETH"); err != nil {
+		return false, err
+	}
+	if _, err := destination.StargatePool("ETH"); err != nil {
+		return false, err
+	}
+	s.toChainId = destination.LayerZeroChainId
+	s.eth = token.Native(ac.Chain)
+	valueSupplier, err := s.eth.Supplier(s.MinAmount, s.MaxAmount)
+	if err != nil {
+		return false, err
+	}
+
+	routerAddress, err := ac.Chain.Contract(chain.StargateRouter)
+	if err != nil {
+		return false, err
+	}
+	routerEthAddress, err := ac.Chain.Contract(chain.StargateRouterEth)
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("Creating StargateRouter and StargateRouterEth contract instances on %s\n", ac.Chain.Name)
+	s.router, err = stargateRouter.NewStargateRouter(routerAddress, ac.Client)
+	if err != nil {
+		return false, err
+	}
+	s.routerEth, err = stargateRouterEth.NewStargateRouterEth(routerEthAddress, ac.Client)
+	if err != nil {
+		return false, err
+	}
+
+	// Keep enough ETH to pay the LayerZero fee and the gas of the swap
+	fees, err := s.quoteFee(ac)
+	if err != nil {
+		return false, err
+	}
+	gasPrice, err := ac.Client.SuggestGasPrice(ac.Context)
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("Generating a random value to swap between %s and %s %s\n", s.MinAmount, s.MaxAmount, s.eth.Symbol)
+	balance, err := ac.Client.BalanceAt(ac.Context, ac.Account.Address, nil)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Error getting account balance [%s]: %v", ac.Account.Address.Hex(), err))
+	}
+	available, reserve := stargateEthAvailable(balance, gasPrice, fees)
+	if available.Cmp(valueSupplier.Min()) < 0 {
+		return false, errors.New(fmt.Sprintf("Account [%s] has not enough ETH balance to execute transfer, %s ETH reserved for fees", ac.Account.Address.Hex(), s.eth.Format(reserve)))
+	}
+	// Make sure our value is not bigger than the account balance left after fees
+	s.value, err = drawValue(ac, valueSupplier, available)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *StargateSwapETH) Execute(ac ActivityContext) (bool, error) {
+	ac.Transactor.Context = ac.Context
+	log.Printf("[%s] started cross swapping %s ETH from %s to %s using Stargate\n", ac.Account.Address.Hex(), s.eth.Format(s.value), ac.Chain.Name, s.ToChain)
+
+	fees, err := s.quoteFee(ac)
+	if err != nil {
+		return false, err
+	}
+	minAmount := applySlippage(s.value, s.SlippageBps)
+	log.Printf("[%s] LZ Quote Fees: %v, min received: %v\n", ac.Account.Address, fees, minAmount)
+
+	// The fee is paid on top of the bridged value
+	ac.Transactor.Value = new(big.Int).Add(s.value, fees)
+	ac.Transactor.GasLimit = stargateSwapEthGasLimit
+	tx, err := s.routerEth.SwapETH(
+		ac.Transactor,
+		s.toChainId,
+		ac.Account.Address,
+		ac.Account.Address.Bytes(),
+		s.value,
+		minAmount,
+	)
+	ac.Transactor.Value = big.NewInt(0)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("StargateRouterEth swapETH tx sent: %s", tx.Hash().Hex())
+	receipt, err := ac.WaitForReceipt(tx)
+	if err != nil {
+		return false, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return false, errors.New(fmt.Sprintf("StargateRouterEth swapETH tx failed: %s", receipt.TxHash.Hex()))
+	}
+	log.Printf("StargateRouterEth swapETH tx confirmed: %s", receipt.TxHash.Hex())
+	return true, nil
+}
+
+// stargateEthAvailable returns the part of the balance that can be bridged once the gas of the swap and the
+// LayerZero fee are reserved, negative when the balance does not cover them, and the reserve.
+func stargateEthAvailable(balance *big.Int, gasPrice *big.Int, fees *big.Int) (*big.Int, *big.Int) {
+	reserve := new(big.Int).Mul(gasPrice, big.NewInt(stargateSwapEthGasLimit))
+	reserve.Add(reserve, fees)
+	return new(big.Int).Sub(balance, reserve), reserve
+}
+
+// quoteFee quotes the LayerZero fee of the swap, RouterETH swaps without destination call nor airdrop.
+func (s *StargateSwapETH) quoteFee(ac ActivityContext) (*big.Int, error) {
+	lzTxObj, err := stargateLzTxObj(layerzero.NewAdapterParams(0))
+	if err != nil {
+		return nil, err
+	}
+	return quoteStargateFee(ac, s.router, s.toChainId, lzTxObj)
+}
@@ -0,0 +1,54 @@
+package activity
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestStargateEthAvailable(t *testing.T) {
+	gwei := big.NewInt(1000000000)
+	tests := []struct {
+		name        string
+		balance     *big.Int
+		gasPrice    *big.Int
+		fees        *big.Int
+		wantReserve *big.Int
+		want        *big.Int
+	}{
+		{
+			name:        "gas and fee reserved",
+			balance:     big.NewInt(1000000000000000000),
+			gasPrice:    gwei,
+			fees:        big.NewInt(300000000000000),
+			wantReserve: big.NewInt(900000000000000),
+			want:        big.NewInt(999100000000000000),
+		},
+		{
+			name:        "no fee",
+			balance:     big.NewInt(1000000000000000),
+			gasPrice:    gwei,
+			fees:        big.NewInt(0),
+			wantReserve: big.NewInt(600000000000000),
+			want:        big.NewInt(400000000000000),
+		},
+		{
+			name:        "balance below the reserve",
+			balance:     big.NewInt(100000000000000),
+			gasPrice:    gwei,
+			fees:        big.NewInt(300000000000000),
+			wantReserve: big.NewInt(900000000000000),
+			want:        big.NewInt(-800000000000000),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			available, reserve := stargateEthAvailable(tt.balance, tt.gasPrice, tt.fees)
+			if reserve.Cmp(tt.wantReserve) != 0 {
+				t.Errorf("reserve = %v, want %v", reserve, tt.wantReserve)
+			}
+			if available.Cmp(tt.want) != 0 {
+				t.Errorf("available = %v, want %v", available, tt.want)
+			}
+		})
+	}
+}
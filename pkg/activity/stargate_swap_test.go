@@ -0,0 +1,126 @@
+package activity
+
+import (
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/layerzero"
+	"bytes"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+)
+
+func TestStargateLzTxObj(t *testing.T) {
+	account := common.HexToAddress("0x2297aEbD383787A160DD0d9F71508148769342E3")
+	tests := []struct {
+		name           string
+		params         layerzero.AdapterParams
+		wantGas        int64
+		wantAmount     int64
+		wantNativeAddr []byte
+		wantErr        bool
+	}{
+		{
+			name:           "plain swap",
+			params:         layerzero.NewAdapterParams(0),
+			wantNativeAddr: []byte{},
+		},
+		{
+			name:           "destination call",
+			params:         layerzero.NewAdapterParams(200000),
+			wantGas:        200000,
+			wantNativeAddr: []byte{},
+		},
+		{
+			name:           "airdrop to the account",
+			params:         layerzero.NewAirdropAdapterParams(0, big.NewInt(1e16), account),
+			wantAmount:     1e16,
+			wantNativeAddr: account.Bytes(),
+		},
+		{
+			name:    "airdrop without address",
+			params:  layerzero.NewAirdropAdapterParams(0, big.NewInt(1e16), common.Address{}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lzTxObj, err := stargateLzTxObj(tt.params)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("stargateLzTxObj() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("stargateLzTxObj() error = %v", err)
+			}
+			if lzTxObj.DstGasForCall.Int64() != tt.wantGas {
+				t.Errorf("DstGasForCall = %v, want %d", lzTxObj.DstGasForCall, tt.wantGas)
+			}
+			if lzTxObj.DstNativeAmount.Int64() != tt.wantAmount {
+				t.Errorf("DstNativeAmount = %v, want %d", lzTxObj.DstNativeAmount, tt.wantAmount)
+			}
+			if !bytes.Equal(lzTxObj.DstNativeAddr, tt.wantNativeAddr) {
+				t.Errorf("DstNativeAddr = %x, want %x", lzTxObj.DstNativeAddr, tt.wantNativeAddr)
+			}
+		})
+	}
+}
+
+func TestAirdropCost(t *testing.T) {
+	tests := []struct {
+		name           string
+		fees           int64
+		airdropFees    int64
+		maxCost        *big.Int
+		wantCost       int64
+		wantAffordable bool
+	}{
+		{"no maximum", 100, 1000, nil, 900, true},
+		{"below the maximum", 100, 150, big.NewInt(100), 50, true},
+		{"at the maximum", 100, 200, big.NewInt(100), 100, true},
+		{"above the maximum", 100, 201, big.NewInt(100), 101, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost, affordable := airdropCost(big.NewInt(tt.fees), big.NewInt(tt.airdropFees), tt.maxCost)
+			if cost.Int64() != tt.wantCost || affordable != tt.wantAffordable {
+				t.Errorf("airdropCost() = %v, %v, want %d, %v", cost, affordable, tt.wantCost, tt.wantAffordable)
+			}
+		})
+	}
+}
+
+func TestStargateSwapResolveAirdrop(t *testing.T) {
+	registry := chain.DefaultRegistry()
+	avalanche, err := registry.Get(chain.Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	polygon, err := registry.Get(chain.Polygon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ac := ActivityContext{Chain: avalanche}
+
+	s := NewStargateSwap(chain.Avalanche, chain.Polygon, "USDC", "USDC", "1", "2")
+	if err := s.resolveAirdrop(ac, polygon); err != nil || s.airdrop != nil || s.maxAirdropCost != nil {
+		t.Errorf("resolveAirdrop() without airdrop = %v, %v, %v", s.airdrop, s.maxAirdropCost, err)
+	}
+
+	s.MinAirdrop, s.MaxAirdrop, s.MaxAirdropCost = "0.5", "1.5", "0.02"
+	if err := s.resolveAirdrop(ac, polygon); err != nil {
+		t.Fatal(err)
+	}
+	if s.airdrop.Min().String() != "500000000000000000" || s.airdrop.Max().String() != "1500000000000000000" {
+		t.Errorf("airdrop = [%v, %v], want 0.5 and 1.5 MATIC in wei", s.airdrop.Min(), s.airdrop.Max())
+	}
+	if s.maxAirdropCost.String() != "20000000000000000" {
+		t.Errorf("maxAirdropCost = %v, want 0.02 AVAX in wei", s.maxAirdropCost)
+	}
+
+	s.MinAirdrop = "2"
+	if err := s.resolveAirdrop(ac, polygon); err == nil {
+		t.Error("resolveAirdrop() error = nil, want an error for a minimum above the maximum")
+	}
+}
@@ -0,0 +1,37 @@
+package activity
+
+import (
+	"math/big"
+	"time"
+)
+
+// Defaults of the swap activities.
+const (
+	DefaultSlippageBps = 50
+	// DefaultBridgeSlippageBps is wider than swaps, the received amount of Stargate bridges also pays the pool fees
+	DefaultBridgeSlippageBps = 100
+	DefaultDeadline          = 5 * time.Minute
+)
+
+// applySlippage returns the amount reduced by the slippage in basis points.
+func applySlippage(amount *big.Int, slippageBps uint64) *big.Int {
+	if slippageBps >= 10000 {
+		return big.NewInt(0)
+	}
+	reduced := new(big.Int).Mul(amount, new(big.Int).SetUint64(10000-slippageBps))
+	return reduced.Div(reduced, big.NewInt(10000))
+}
+
+// priceImpactBps returns how much worse the price of the amount is than the reference price, in basis points.
+// A negative impact means the amount gets a better price than the reference.
+func priceImpactBps(referenceIn *big.Int, referenceOut *big.Int, amountIn *big.Int, amountOut *big.Int) int64 {
+	// Amount expected at the reference price, compared to the quoted amount
+	expected := new(big.Int).Mul(referenceOut, amountIn)
+	expected.Div(expected, referenceIn)
+	if expected.Sign() == 0 {
+		return 0
+	}
+	impact := new(big.Int).Sub(expected, amountOut)
+	impact.Mul(impact, big.NewInt(10000))
+	return impact.Div(impact, expected).Int64()
+}
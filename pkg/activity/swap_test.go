@@ -0,0 +1,65 @@
+package activity
+
+import (
+	"activity-bot/pkg/token"
+	"math/big"
+	"testing"
+)
+
+func TestApplySlippage(t *testing.T) {
+	tests := []struct {
+		amount      int64
+		slippageBps uint64
+		want        int64
+	}{
+		{1000000, 0, 1000000},
+		{1000000, 50, 995000},
+		{1000000, 10000, 0},
+		{1000000, 20000, 0},
+		{999, 50, 994},
+	}
+	for _, test := range tests {
+		got := applySlippage(big.NewInt(test.amount), test.slippageBps)
+		if got.Cmp(big.NewInt(test.want)) != 0 {
+			t.Errorf("applySlippage(%d, %d) = %s, want %d", test.amount, test.slippageBps, got, test.want)
+		}
+	}
+}
+
+func TestPriceImpactBps(t *testing.T) {
+	tests := []struct {
+		name                                           string
+		referenceIn, referenceOut, amountIn, amountOut int64
+		want                                           int64
+	}{
+		{"same price", 100, 200, 1000, 2000, 0},
+		{"one percent worse", 100, 200, 1000, 1980, 100},
+		{"better price", 100, 200, 1000, 2020, -100},
+		{"no reference liquidity", 100, 0, 1000, 2000, 0},
+	}
+	for _, test := range tests {
+		got := priceImpactBps(big.NewInt(test.referenceIn), big.NewInt(test.referenceOut), big.NewInt(test.amountIn), big.NewInt(test.amountOut))
+		if got != test.want {
+			t.Errorf("%s: priceImpactBps() = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPriceReferenceAmount(t *testing.T) {
+	usdc := &token.Token{Symbol: "USDC", Decimals: 6}
+	if got, err := priceReferenceAmount(usdc, "0.5"); err != nil || got.Int64() != 500000 {
+		t.Errorf("priceReferenceAmount() = %v, %v, want the configured 500000", got, err)
+	}
+	if _, err := priceReferenceAmount(usdc, "0.0000001"); err == nil {
+		t.Error("priceReferenceAmount() error = nil, want an error for more than 6 decimals")
+	}
+	reference, err := priceReferenceAmount(usdc, "")
+	if err != nil || reference.Int64() != 1000000 {
+		t.Errorf("priceReferenceAmount() = %v, %v, want one whole token of 1000000", reference, err)
+	}
+
+	// A value of 50 units, too small for a hundredth of it, is still compared to the whole token price
+	if got := priceImpactBps(reference, big.NewInt(2000000), big.NewInt(50), big.NewInt(99)); got != 100 {
+		t.Errorf("priceImpactBps() of a small value = %d, want 100", got)
+	}
+}
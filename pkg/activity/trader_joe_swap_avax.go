@@ -0,0 +1,275 @@
+package activity
+
+import (
+	"activity-bot/pkg/abi/erc20"
+	"activity-bot/pkg/abi/traderJoeAvax"
+	"activity-bot/pkg/abi/traderJoeFactoryAvax"
+	"activity-bot/pkg/abi/traderJoePairAvax"
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/token"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+	"time"
+)
+
+// traderJoeVersionV2_1 is the ILBRouter.Version of the Liquidity Book v2.1 pairs.
+const traderJoeVersionV2_1 = 2
+
+// TraderJoeSwapAvax swaps on the Trader Joe Liquidity Book router. Either token can be AVAX to swap from or
+// to the native token, the router wraps it through WAVAX.
+type TraderJoeSwapAvax struct {
+	FromToken     string        // Symbol in the chain's token book or AVAX
+	ToToken       string        // Symbol in the chain's token book or AVAX
+	BinSteps      []uint64      // Bin step of the pair used for each hop
+	Via           []string      // Optional symbols of intermediate tokens, one less than the bin steps
+	SlippageBps   uint64        // Accepted difference between the quote and the received amount
+	Deadline      time.Duration // How long the swap stays valid once sent
+	MinAmount     string        // Decimal amount of FromToken, such as "12.5"
+	MaxAmount     string        // Decimal amount of FromToken
+	router        *traderJoeAvax.TraderJoeAvax
+	routerAddress common.Address
+	fromToken     *token.Token     // Computed on can execute
+	toToken       *token.Token     // Computed on can execute
+	fromErc20     *erc20.Erc20     // Nil when swapping from AVAX
+	tokenPath     []common.Address // Computed on can execute
+	pairs         []common.Address // Computed on can execute
+	value         *big.Int         // Computed on can execute
+}
+
+func NewTraderJoeSwapAvax(fromToken string, toToken string, binStep uint64, minAmount string, maxAmount string) *TraderJoeSwapAvax {
+	return &TraderJoeSwapAvax{
+		FromToken:   fromToken,
+		ToToken:     toToken,
+		BinSteps:    []uint64{binStep},
+		SlippageBps: DefaultSlippageBps,
+		Deadline:    DefaultDeadline,
+		MinAmount:   minAmount,
+		MaxAmount:   maxAmount,
+	}
+}
+
+func (t *TraderJoeSwapAvax) Chain() string {
+	return chain.Avalanche
+}
+
+func (t *TraderJoeSwapAvax) Contracts(c *chain.Chain) ([]common.Address, error) {
+	tokens := make([]string, 0, len(t.Via)+2)
+	for _, symbol := range append([]string{t.FromToken, t.ToToken}, t.Via...) {
+		if symbol != c.NativeSymbol {
+			tokens = append(tokens, symbol)
+		}
+	}
+	return addressBook(c, []string{chain.TraderJoeRouter}, tokens)
+}
+
+func (t *TraderJoeSwapAvax) CanExecute(ac ActivityContext) (bool, error) {
+	if t.FromToken == t.ToToken {
+		return false, errors.New("TraderJoeSwapAvax needs different tokens")
+	}
+	if len(t.BinSteps) != len(t.Via)+1 {
+		return false, errors.New(fmt.Sprintf("TraderJoeSwapAvax needs %d bin steps, got %d", len(t.Via)+1, len(t.BinSteps)))
+	}
+	routerAddress, err := ac.Chain.Contract(chain.TraderJoeRouter)
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("Creating TraderJoeAvax contract instance\n")
+	router, err := traderJoeAvax.NewTraderJoeAvax(routerAddress, ac.Client)
+	if err != nil {
+		return false, err
+	}
+	t.router = router
+	t.routerAddress = routerAddress
+
+	t.fromToken, err = token.Resolve(ac.CallOpts(), ac.Chain, ac.Client, t.FromToken)
+	if err != nil {
+		return false, err
+	}
+	t.toToken, err = token.Resolve(ac.CallOpts(), ac.Chain, ac.Client, t.ToToken)
+	if err != nil {
+		return false, err
+	}
+	valueSupplier, err := t.fromToken.Supplier(t.MinAmount, t.MaxAmount)
+	if err != nil {
+		return false, err
+	}
+	if err := t.resolvePath(ac); err != nil {
+		return false, err
+	}
+
+	log.Printf("Generating a random value to swap between %s and %s %s\n", t.MinAmount, t.MaxAmount, t.FromToken)
+	var balance *big.Int
+	t.fromErc20 = nil
+	if t.fromToken.IsNative() {
+		balance, err = ac.Client.BalanceAt(ac.Context, ac.Account.Address, nil)
+	} else {
+		t.fromErc20, err = erc20.NewErc20(t.fromToken.Address, ac.Client)
+		if err != nil {
+			return false, err
+		}
+		balance, err = t.fromErc20.BalanceOf(ac.CallOpts(), ac.Account.Address)
+	}
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Error getting account %s balance [%s]: %v", t.FromToken, ac.Account.Address.Hex(), err))
+	}
+	t.value, err = drawValue(ac, valueSupplier, balance)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// resolvePath builds the token path, replacing AVAX with WAVAX, and looks up the pair of each hop.
+func (t *TraderJoeSwapAvax) resolvePath(ac ActivityContext) error {
+	wnative, err := t.router.GetWNATIVE(ac.CallOpts())
+	if err != nil {
+		return err
+	}
+	factoryAddress, err := t.router.GetFactory(ac.CallOpts())
+	if err != nil {
+		return err
+	}
+	factory, err := traderJoeFactoryAvax.NewTraderJoeFactoryAvax(factoryAddress, ac.Client)
+	if err != nil {
+		return err
+	}
+
+	via := make([]common.Address, len(t.Via))
+	for i, symbol := range t.Via {
+		via[i] = chain.NativeToken
+		if symbol != ac.Chain.NativeSymbol {
+			via[i], err = ac.Chain.Token(symbol)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	t.tokenPath = traderJoeTokenPath(t.fromToken.Address, via, t.toToken.Address, wnative)
+
+	t.pairs = make([]common.Address, len(t.BinSteps))
+	for i, binStep := range t.BinSteps {
+		info, err := factory.GetLBPairInformation(ac.CallOpts(), t.tokenPath[i], t.tokenPath[i+1], new(big.Int).SetUint64(binStep))
+		if err != nil {
+			return err
+		}
+		if info.LBPair == (common.Address{}) {
+			return errors.New(fmt.Sprintf("No Trader Joe pair for %s and %s with bin step %d", t.tokenPath[i].Hex(), t.tokenPath[i+1].Hex(), binStep))
+		}
+		t.pairs[i] = info.LBPair
+	}
+	return nil
+}
+
+// traderJoeTokenPath returns the tokens of each hop from the source to the destination token, AVAX being
+// replaced with the wrapped native token the router swaps.
+func traderJoeTokenPath(from common.Address, via []common.Address, to common.Address, wnative common.Address) []common.Address {
+	path := make([]common.Address, 0, len(via)+2)
+	for _, token := range append(append([]common.Address{from}, via...), to) {
+		if token == chain.NativeToken {
+			token = wnative
+		}
+		path = append(path, token)
+	}
+	return path
+}
+
+// traderJoeRouterPath returns the router path of the token path through the Liquidity Book v2.1 pairs with the
+// bin steps.
+func traderJoeRouterPath(binSteps []uint64, tokenPath []common.Address) traderJoeAvax.ILBRouterPath {
+	path := traderJoeAvax.ILBRouterPath{
+		PairBinSteps: make([]*big.Int, len(binSteps)),
+		Versions:     make([]uint8, len(binSteps)),
+		TokenPath:    tokenPath,
+	}
+	for i, binStep := range binSteps {
+		path.PairBinSteps[i] = new(big.Int).SetUint64(binStep)
+		path.Versions[i] = traderJoeVersionV2_1
+	}
+	return path
+}
+
+// quote returns the amount received for the value following each hop of the path.
+func (t *TraderJoeSwapAvax) quote(ac ActivityContext) (*big.Int, error) {
+	amount := t.value
+	for i, pairAddress := range t.pairs {
+		pair, err := traderJoePairAvax.NewTraderJoePairAvax(pairAddress, ac.Client)
+		if err != nil {
+			return nil, err
+		}
+		tokenY, err := pair.GetTokenY(ac.CallOpts())
+		if err != nil {
+			return nil, err
+		}
+		out, err := t.router.GetSwapOut(ac.CallOpts(), pairAddress, amount, tokenY == t.tokenPath[i+1])
+		if err != nil {
+			return nil, err
+		}
+		if out.AmountInLeft.Sign() > 0 {
+			return nil, errors.New(fmt.Sprintf("Not enough liquidity in Trader Joe pair %s, %s left", pairAddress.Hex(), out.AmountInLeft.String()))
+		}
+		amount = out.AmountOut
+	}
+	return amount, nil
+}
+
+func (t *TraderJoeSwapAvax) Execute(ac ActivityContext) (bool, error) {
+	ac.Transactor.Context = ac.Context
+	log.Printf("[%s] started swapping %s %s to %s using TraderJoeSwapAvax\n", ac.Account.Address.Hex(), t.fromToken.Format(t.value), t.FromToken, t.ToToken)
+
+	if t.fromErc20 != nil {
+		log.Println("Checking if allowance required")
+		if err := ensureAllowance(ac, t.fromErc20, t.routerAddress, t.value); err != nil {
+			return false, err
+		}
+	}
+
+	quote, err := t.quote(ac)
+	if err != nil {
+		return false, err
+	}
+	minAmountOut := applySlippage(quote, t.SlippageBps)
+	log.Printf("[%s] Trader Joe quote: %s, min amount out: %s\n", ac.Account.Address.Hex(), quote.String(), minAmountOut.String())
+
+	path := traderJoeRouterPath(t.BinSteps, t.tokenPath)
+	deadline := big.NewInt(time.Now().Add(t.Deadline).Unix())
+
+	var tx *types.Transaction
+	ac.Transactor.GasLimit = uint64(400000)
+	switch {
+	case t.fromToken.IsNative():
+		ac.Transactor.Value = t.value
+		tx, err = t.router.SwapExactNATIVEForTokens(ac.Transactor, minAmountOut, path, ac.Account.Address, deadline)
+	case t.toToken.IsNative():
+		ac.Transactor.Value = big.NewInt(0)
+		tx, err = t.router.SwapExactTokensForNATIVE(ac.Transactor, t.value, minAmountOut, path, ac.Account.Address, deadline)
+	default:
+		ac.Transactor.Value = big.NewInt(0)
+		tx, err = t.router.SwapExactTokensForTokens(ac.Transactor, t.value, minAmountOut, path, ac.Account.Address, deadline)
+	}
+	ac.Transactor.Value = big.NewInt(0)
+	if err != nil {
+		return false, err
+	}
+	receipt, err := ac.WaitForReceipt(tx)
+	if err != nil {
+		return false, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return false, errors.New(fmt.Sprintf("Transaction failed with status: %d", receipt.Status))
+	}
+
+	log.Printf("[%s] swap of %s %s to %s %s completed, transaction hash: %s\n",
+		ac.Account.Address.Hex(),
+		t.fromToken.Format(t.value),
+		t.FromToken,
+		t.toToken.Format(quote),
+		t.ToToken,
+		tx.Hash().Hex())
+	return true, nil
+}
@@ -0,0 +1,50 @@
+package activity
+
+import (
+	"activity-bot/pkg/chain"
+	"github.com/ethereum/go-ethereum/common"
+	"reflect"
+	"testing"
+)
+
+var (
+	wavax = common.HexToAddress("0xB31f66AA3C1e785363F0875A1B74E27b85FD66c7")
+	usdc  = common.HexToAddress("0xB97EF9Ef8734C71904D8002F8b6Bc66Dd9c48a6E")
+	usdt  = common.HexToAddress("0x9702230A8Ea53601f5cD2dc00fDBc13d4dF4A8c7")
+)
+
+func TestTraderJoeTokenPath(t *testing.T) {
+	tests := []struct {
+		name string
+		from common.Address
+		via  []common.Address
+		to   common.Address
+		want []common.Address
+	}{
+		{"from AVAX", chain.NativeToken, nil, usdc, []common.Address{wavax, usdc}},
+		{"to AVAX", usdc, nil, chain.NativeToken, []common.Address{usdc, wavax}},
+		{"tokens", usdc, nil, usdt, []common.Address{usdc, usdt}},
+		{"through AVAX", usdc, []common.Address{chain.NativeToken}, usdt, []common.Address{usdc, wavax, usdt}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := traderJoeTokenPath(tt.from, tt.via, tt.to, wavax); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("traderJoeTokenPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTraderJoeRouterPath(t *testing.T) {
+	tokenPath := []common.Address{usdc, wavax, usdt}
+	path := traderJoeRouterPath([]uint64{20, 1}, tokenPath)
+	if len(path.PairBinSteps) != 2 || path.PairBinSteps[0].Uint64() != 20 || path.PairBinSteps[1].Uint64() != 1 {
+		t.Errorf("PairBinSteps = %v, want [20 1]", path.PairBinSteps)
+	}
+	if !reflect.DeepEqual(path.Versions, []uint8{traderJoeVersionV2_1, traderJoeVersionV2_1}) {
+		t.Errorf("Versions = %v, want v2.1 for every hop", path.Versions)
+	}
+	if !reflect.DeepEqual(path.TokenPath, tokenPath) {
+		t.Errorf("TokenPath = %v, want %v", path.TokenPath, tokenPath)
+	}
+}
@@ -2,7 +2,7 @@ package activity
 
 import (
 	"activity-bot/pkg/random"
-	"activity-bot/pkg/util"
+	"activity-bot/pkg/token"
 	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
@@ -14,49 +14,46 @@ import (
 const gasLimit uint64 = 21000
 
 type TransferNative struct {
+	chain         string
 	to            common.Address
 	valueSupplier *random.Supplier
+	Result        *TransferResult // Set on execute once the transfer is mined
 	// Computed on can execute
 	value *big.Int
 }
 
-func NewTransferNative(to common.Address, valueSupplier *random.Supplier) *TransferNative {
+func NewTransferNative(chain string, to common.Address, valueSupplier *random.Supplier) *TransferNative {
 	return &TransferNative{
+		chain:         chain,
 		to:            to,
 		valueSupplier: valueSupplier,
 	}
 }
 
+func (t *TransferNative) Chain() string {
+	return t.chain
+}
+
 func (t *TransferNative) CanExecute(ac ActivityContext) (bool, error) {
 	accountBalance, err := ac.Client.BalanceAt(ac.Context, ac.Account.Address, nil)
 	if err != nil {
 		return false, errors.New(fmt.Sprintf("Error getting account balance [%s]: %v", ac.Account.Address.Hex(), err))
 	}
 
-	if accountBalance.Cmp(t.valueSupplier.Min()) < 0 {
-		return false, errors.New(fmt.Sprintf("Account [%s] has not enough balance to execute transfer", ac.Account.Address.Hex()))
-	}
-
-	t.value = t.valueSupplier.Supply()
-	// Make sure our value is not bigger than the account balance
-	for {
-		if t.value.Cmp(accountBalance) > 0 {
-			t.value = t.valueSupplier.Supply()
-		} else {
-			break
-		}
+	t.value, err = drawValue(ac, t.valueSupplier, accountBalance)
+	if err != nil {
+		return false, err
 	}
 
 	return true, nil
 }
 
 func (t *TransferNative) Execute(ac ActivityContext) (bool, error) {
+	ac.Transactor.Context = ac.Context
 	log.Printf("[%s] started transfering %s wei to [%s]\n", ac.Account.Address.Hex(), t.value.String(), t.to)
+	t.Result = nil
 
-	chainId, err := ac.Client.ChainID(ac.Context)
-	if err != nil {
-		return false, err
-	}
+	chainId := ac.Chain.EvmChainId()
 	nonce, err := ac.Client.PendingNonceAt(ac.Context, ac.Account.Address)
 	if err != nil {
 		return false, err
@@ -91,7 +88,7 @@ func (t *TransferNative) Execute(ac ActivityContext) (bool, error) {
 		return false, err
 	}
 
-	receipt, err := util.WaitForReceipt(signedTx, ac.Waiter)
+	receipt, err := ac.WaitForReceipt(signedTx)
 	if err != nil {
 		return false, err
 	}
@@ -99,10 +96,49 @@ func (t *TransferNative) Execute(ac ActivityContext) (bool, error) {
 		return false, errors.New(fmt.Sprintf("Transaction failed with status: %d", receipt.Status))
 	}
 
-	log.Printf("[%s] transfer of %s wei to [%s] completed, transaction hash: %s\n",
-		ac.Account.Address.Hex(),
-		t.value.String(),
-		t.to,
-		signedTx.Hash().Hex())
+	t.Result = &TransferResult{
+		Token:  token.Native(ac.Chain),
+		From:   ac.Account.Address,
+		To:     t.to,
+		Amount: t.value,
+		Tx:     signedTx.Hash(),
+	}
+	t.Result.Report()
 	return true, nil
 }
+
+// TransferResult records a mined transfer.
+type TransferResult struct {
+	Token  *token.Token
+	From   common.Address
+	To     common.Address
+	Amount *big.Int // In the token minimal unit
+	Tx     common.Hash
+}
+
+func (r *TransferResult) Report() {
+	log.Printf("[%s] transfer of %s %s to [%s] completed, transaction hash: %s\n",
+		r.From.Hex(),
+		r.Token.Format(r.Amount),
+		r.Token.Symbol,
+		r.To.Hex(),
+		r.Tx.Hex())
+}
+
+// drawValue returns a value of the supplier which is not bigger than the balance.
+func drawValue(ac ActivityContext, supplier *random.Supplier, balance *big.Int) (*big.Int, error) {
+	if balance.Cmp(supplier.Min()) < 0 {
+		return nil, errors.New(fmt.Sprintf("Account [%s] has not enough balance to execute transfer", ac.Account.Address.Hex()))
+	}
+
+	value := supplier.Supply()
+	// Make sure our value is not bigger than the account balance
+	for {
+		if value.Cmp(balance) > 0 {
+			value = supplier.Supply()
+		} else {
+			break
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,149 @@
+package activity
+
+import (
+	"activity-bot/pkg/abi/erc20"
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/random"
+	"activity-bot/pkg/token"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+)
+
+// Recipient returns the address a transfer is sent to.
+type Recipient func(ac ActivityContext) (common.Address, error)
+
+// FixedRecipient always sends to the address.
+func FixedRecipient(to common.Address) Recipient {
+	return func(ac ActivityContext) (common.Address, error) {
+		return to, nil
+	}
+}
+
+// AccountRecipient sends to one of the managed accounts other than the sender.
+func AccountRecipient(managed []accounts.Account) Recipient {
+	return func(ac ActivityContext) (common.Address, error) {
+		others := make([]common.Address, 0, len(managed))
+		for _, account := range managed {
+			if account.Address != ac.Account.Address {
+				others = append(others, account.Address)
+			}
+		}
+		if len(others) == 0 {
+			return common.Address{}, errors.New(fmt.Sprintf("No managed account other than [%s] to transfer to", ac.Account.Address.Hex()))
+		}
+		return random.Pick(others), nil
+	}
+}
+
+// AddressBookRecipient sends to an address of the book chosen at random.
+func AddressBookRecipient(book []common.Address) Recipient {
+	return func(ac ActivityContext) (common.Address, error) {
+		if len(book) == 0 {
+			return common.Address{}, errors.New("Address book is empty")
+		}
+		return random.Pick(book), nil
+	}
+}
+
+// TransferToken transfers an ERC-20 of the chain's token book.
+type TransferToken struct {
+	chain     string
+	Token     string // Symbol in the chain's token book
+	Recipient Recipient
+	MinAmount string          // Decimal amount of the token, such as "12.5"
+	MaxAmount string          // Decimal amount of the token
+	Result    *TransferResult // Set on execute once the transfer is mined
+	erc20     *erc20.Erc20
+	token     *token.Token
+	to        common.Address // Computed on can execute
+	value     *big.Int       // Computed on can execute
+}
+
+func NewTransferToken(chain string, token string, recipient Recipient, minAmount string, maxAmount string) *TransferToken {
+	return &TransferToken{
+		chain:     chain,
+		Token:     token,
+		Recipient: recipient,
+		MinAmount: minAmount,
+		MaxAmount: maxAmount,
+	}
+}
+
+func (t *TransferToken) Chain() string {
+	return t.chain
+}
+
+func (t *TransferToken) Contracts(c *chain.Chain) ([]common.Address, error) {
+	return addressBook(c, nil, []string{t.Token})
+}
+
+func (t *TransferToken) CanExecute(ac ActivityContext) (bool, error) {
+	var err error
+	t.token, err = token.Resolve(ac.CallOpts(), ac.Chain, ac.Client, t.Token)
+	if err != nil {
+		return false, err
+	}
+	if t.token.IsNative() {
+		return false, errors.New(fmt.Sprintf("TransferToken transfers ERC-20 tokens, %s is native", t.Token))
+	}
+	log.Printf("Creating %s contract instance\n", t.Token)
+	t.erc20, err = erc20.NewErc20(t.token.Address, ac.Client)
+	if err != nil {
+		return false, err
+	}
+	valueSupplier, err := t.token.Supplier(t.MinAmount, t.MaxAmount)
+	if err != nil {
+		return false, err
+	}
+
+	t.to, err = t.Recipient(ac)
+	if err != nil {
+		return false, err
+	}
+
+	balance, err := t.erc20.BalanceOf(ac.CallOpts(), ac.Account.Address)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Error getting account %s balance [%s]: %v", t.Token, ac.Account.Address.Hex(), err))
+	}
+	t.value, err = drawValue(ac, valueSupplier, balance)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (t *TransferToken) Execute(ac ActivityContext) (bool, error) {
+	ac.Transactor.Context = ac.Context
+	log.Printf("[%s] started transfering %s %s to [%s]\n", ac.Account.Address.Hex(), t.token.Format(t.value), t.Token, t.to)
+	t.Result = nil
+
+	ac.Transactor.Value = big.NewInt(0)
+	ac.Transactor.GasLimit = 0 // Estimated, token transfers vary with the token implementation
+	tx, err := t.erc20.Transfer(ac.Transactor, t.to, t.value)
+	if err != nil {
+		return false, err
+	}
+	receipt, err := ac.WaitForReceipt(tx)
+	if err != nil {
+		return false, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return false, errors.New(fmt.Sprintf("Transaction failed with status: %d", receipt.Status))
+	}
+
+	t.Result = &TransferResult{
+		Token:  t.token,
+		From:   ac.Account.Address,
+		To:     t.to,
+		Amount: t.value,
+		Tx:     tx.Hash(),
+	}
+	t.Result.Report()
+	return true, nil
+}
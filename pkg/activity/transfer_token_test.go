@@ -0,0 +1,27 @@
+package activity
+
+import (
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"testing"
+)
+
+func TestAccountRecipient(t *testing.T) {
+	sender := accounts.Account{Address: common.HexToAddress("0x1111111111111111111111111111111111111111")}
+	other := accounts.Account{Address: common.HexToAddress("0x2222222222222222222222222222222222222222")}
+	ac := ActivityContext{Account: &sender}
+
+	for i := 0; i < 10; i++ {
+		got, err := AccountRecipient([]accounts.Account{sender, other})(ac)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != other.Address {
+			t.Errorf("AccountRecipient() = %s, want %s", got.Hex(), other.Address.Hex())
+		}
+	}
+
+	if _, err := AccountRecipient([]accounts.Account{sender})(ac); err == nil {
+		t.Error("AccountRecipient() error = nil, want an error without other account")
+	}
+}
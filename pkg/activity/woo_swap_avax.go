@@ -1,81 +1,182 @@
 package activity
 
 import (
+	"activity-bot/pkg/abi/erc20"
 	"activity-bot/pkg/abi/wooRouterAvax"
-	"activity-bot/pkg/constants"
-	"activity-bot/pkg/random"
-	"activity-bot/pkg/util"
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/token"
 	"errors"
 	"fmt"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"log"
 	"math/big"
 )
 
+// WooSwapAvax swaps on the WooFi router. FromToken is either AVAX to sell the native token or an ERC-20 such
+// as USDC, USDC.e, BTC.b or WAVAX, which is approved to the router when needed.
 type WooSwapAvax struct {
-	FromToken     common.Address
-	ToToken       common.Address
-	ValueSupplier *random.Supplier
-	wooRouterAvax *wooRouterAvax.WooRouterAvax
-	value         *big.Int // Computed on can execute
+	FromToken         string   // Symbol in the chain's token book or AVAX
+	ToToken           string   // Symbol in the chain's token book or AVAX
+	MinAmount         string   // Decimal amount of FromToken, such as "12.5"
+	MaxAmount         string   // Decimal amount of FromToken
+	SlippageBps       uint64   // Accepted difference between the quote and the received amount
+	MaxPriceImpactBps uint64   // Optional, skips the swap when the quote is worse than the reference price by more
+	ReferenceAmount   string   // Decimal amount quoted for the reference price, defaults to one whole FromToken
+	Received          *big.Int // Set on execute from the swap event
+	wooRouterAvax     *wooRouterAvax.WooRouterAvax
+	wooRouter         common.Address
+	fromToken         *token.Token // Computed on can execute
+	toToken           *token.Token // Computed on can execute
+	fromErc20         *erc20.Erc20 // Nil when selling AVAX
+	value             *big.Int     // Computed on can execute
 }
 
-func NewWooSwapAvax(fromToken string, toToken string, valueSupplier *random.Supplier) *WooSwapAvax {
+func NewWooSwapAvax(fromToken string, toToken string, minAmount string, maxAmount string) *WooSwapAvax {
 	return &WooSwapAvax{
-		FromToken:     common.HexToAddress(fromToken),
-		ToToken:       common.HexToAddress(toToken),
-		ValueSupplier: valueSupplier,
+		FromToken:   fromToken,
+		ToToken:     toToken,
+		SlippageBps: DefaultSlippageBps,
+		MinAmount:   minAmount,
+		MaxAmount:   maxAmount,
 	}
 }
 
+func (w *WooSwapAvax) Chain() string {
+	return chain.Avalanche
+}
+
+func (w *WooSwapAvax) Contracts(c *chain.Chain) ([]common.Address, error) {
+	tokens := make([]string, 0, 2)
+	for _, symbol := range []string{w.FromToken, w.ToToken} {
+		if symbol != c.NativeSymbol {
+			tokens = append(tokens, symbol)
+		}
+	}
+	return addressBook(c, []string{chain.WooRouter}, tokens)
+}
+
 func (w *WooSwapAvax) CanExecute(ac ActivityContext) (bool, error) {
+	wooRouter, err := ac.Chain.Contract(chain.WooRouter)
+	if err != nil {
+		return false, err
+	}
+
 	log.Printf("Creating WooRouterAvax contract instance\n")
-	contract, err := wooRouterAvax.NewWooRouterAvax(common.HexToAddress(constants.AVA_WOO_SWAP_CONTRACT), ac.Client)
+	contract, err := wooRouterAvax.NewWooRouterAvax(wooRouter, ac.Client)
 	if err != nil {
-		log.Fatal(err)
+		return false, err
 	}
 	w.wooRouterAvax = contract
+	w.wooRouter = wooRouter
 
-	log.Printf("Generating a random value to swap using value supplier [%s, %s]\n", w.ValueSupplier.Min().String(), w.ValueSupplier.Max().String())
-	accountBalance, err := ac.Client.BalanceAt(ac.Context, ac.Account.Address, nil)
+	w.fromToken, err = token.Resolve(ac.CallOpts(), ac.Chain, ac.Client, w.FromToken)
 	if err != nil {
-		return false, errors.New(fmt.Sprintf("Error getting account balance [%s]: %v", ac.Account.Address.Hex(), err))
+		return false, err
+	}
+	w.toToken, err = token.Resolve(ac.CallOpts(), ac.Chain, ac.Client, w.ToToken)
+	if err != nil {
+		return false, err
+	}
+	valueSupplier, err := w.fromToken.Supplier(w.MinAmount, w.MaxAmount)
+	if err != nil {
+		return false, err
 	}
 
-	if accountBalance.Cmp(w.ValueSupplier.Min()) < 0 {
-		return false, errors.New(fmt.Sprintf("Account [%s] has not enough balance to execute transfer", ac.Account.Address.Hex()))
+	log.Printf("Generating a random value to swap between %s and %s %s\n", w.MinAmount, w.MaxAmount, w.FromToken)
+	var accountBalance *big.Int
+	w.fromErc20 = nil
+	if w.fromToken.IsNative() {
+		accountBalance, err = ac.Client.BalanceAt(ac.Context, ac.Account.Address, nil)
+	} else {
+		w.fromErc20, err = erc20.NewErc20(w.fromToken.Address, ac.Client)
+		if err != nil {
+			return false, err
+		}
+		accountBalance, err = w.fromErc20.BalanceOf(ac.CallOpts(), ac.Account.Address)
+	}
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Error getting account %s balance [%s]: %v", w.FromToken, ac.Account.Address.Hex(), err))
+	}
+	w.value, err = drawValue(ac, valueSupplier, accountBalance)
+	if err != nil {
+		return false, err
 	}
 
-	w.value = w.ValueSupplier.Supply()
-	// Make sure our value is not bigger than the account balance
-	for {
-		if w.value.Cmp(accountBalance) > 0 {
-			w.value = w.ValueSupplier.Supply()
-		} else {
-			break
+	if w.MaxPriceImpactBps > 0 {
+		impact, err := w.priceImpact(ac)
+		if err != nil {
+			return false, err
+		}
+		if impact > int64(w.MaxPriceImpactBps) {
+			log.Printf("[%s] skipping WooSwapAvax, price impact of %d bps is above %d bps\n", ac.Account.Address.Hex(), impact, w.MaxPriceImpactBps)
+			return false, nil
 		}
 	}
 
 	return true, nil
 }
 
+// priceImpact compares the quote of the value to the price quoted for the reference amount.
+func (w *WooSwapAvax) priceImpact(ac ActivityContext) (int64, error) {
+	referenceAmount, err := priceReferenceAmount(w.fromToken, w.ReferenceAmount)
+	if err != nil {
+		return 0, err
+	}
+	if referenceAmount.Sign() <= 0 {
+		return 0, errors.New(fmt.Sprintf("WooSwapAvax reference amount must be positive, got %s", w.ReferenceAmount))
+	}
+	referenceOut, err := w.wooRouterAvax.QuerySwap(ac.CallOpts(), w.fromToken.Address, w.toToken.Address, referenceAmount)
+	if err != nil {
+		return 0, err
+	}
+	quote, err := w.wooRouterAvax.QuerySwap(ac.CallOpts(), w.fromToken.Address, w.toToken.Address, w.value)
+	if err != nil {
+		return 0, err
+	}
+	return priceImpactBps(referenceAmount, referenceOut, w.value, quote), nil
+}
+
+// priceReferenceAmount returns the configured decimal reference amount of the token, or one whole token. A fixed
+// default keeps the reference quote meaningful for values too small to be divided.
+func priceReferenceAmount(t *token.Token, configured string) (*big.Int, error) {
+	if configured == "" {
+		configured = "1"
+	}
+	return t.Parse(configured)
+}
+
 func (w *WooSwapAvax) Execute(ac ActivityContext) (bool, error) {
-	log.Printf("[%s] started swapping using WooSwapAvax\n", ac.Account.Address.Hex())
+	ac.Transactor.Context = ac.Context
+	log.Printf("[%s] started swapping %s %s to %s using WooSwapAvax\n", ac.Account.Address.Hex(), w.fromToken.Format(w.value), w.FromToken, w.ToToken)
+
+	if w.fromErc20 != nil {
+		log.Println("Checking if allowance required")
+		if err := ensureAllowance(ac, w.fromErc20, w.wooRouter, w.value); err != nil {
+			return false, err
+		}
+	}
 
-	result, err := w.wooRouterAvax.QuerySwap(&bind.CallOpts{}, w.FromToken, w.ToToken, w.value)
+	result, err := w.wooRouterAvax.QuerySwap(ac.CallOpts(), w.fromToken.Address, w.toToken.Address, w.value)
 	if err != nil {
 		return false, err
 	}
+	minToAmount := applySlippage(result, w.SlippageBps)
+	log.Printf("[%s] WooFi quote: %s, min amount out: %s\n", ac.Account.Address.Hex(), result.String(), minToAmount.String())
+	w.Received = nil
 
 	ac.Transactor.GasLimit = uint64(350000)
-	ac.Transactor.Value = w.value
-	tx, err := w.wooRouterAvax.Swap(ac.Transactor, w.FromToken, w.ToToken, w.value, result, ac.Account.Address, ac.Account.Address)
+	// Only native input is paid with the transaction, tokens are pulled through the allowance
+	ac.Transactor.Value = big.NewInt(0)
+	if w.fromErc20 == nil {
+		ac.Transactor.Value = w.value
+	}
+	tx, err := w.wooRouterAvax.Swap(ac.Transactor, w.fromToken.Address, w.toToken.Address, w.value, minToAmount, ac.Account.Address, ac.Account.Address)
+	ac.Transactor.Value = big.NewInt(0)
 	if err != nil {
 		return false, err
 	}
-	receipt, err := util.WaitForReceipt(tx, ac.Waiter)
+	receipt, err := ac.WaitForReceipt(tx)
 	if err != nil {
 		return false, err
 	}
@@ -83,13 +184,33 @@ func (w *WooSwapAvax) Execute(ac ActivityContext) (bool, error) {
 		return false, errors.New(fmt.Sprintf("Transaction failed with status: %d", receipt.Status))
 	}
 
-	log.Printf("[%s] swapp of %s@%s wei to [%s] as %s@%s wei completed, transaction hash: %s\n",
+	// The router reports the amount actually received in its swap event
+	for _, l := range receipt.Logs {
+		if l.Address != w.wooRouter {
+			continue
+		}
+		event, err := w.wooRouterAvax.ParseWooRouterSwap(*l)
+		if err != nil {
+			continue
+		}
+		w.Received = event.ToAmount
+		break
+	}
+	received := "unknown"
+	if w.Received != nil {
+		received = w.toToken.Format(w.Received)
+	} else {
+		log.Printf("[%s] WooRouterSwap event not found in transaction %s\n", ac.Account.Address.Hex(), tx.Hash().Hex())
+	}
+
+	log.Printf("[%s] swap of %s %s to [%s] as %s %s completed (quoted %s), transaction hash: %s\n",
 		ac.Account.Address.Hex(),
-		w.value.String(),
+		w.fromToken.Format(w.value),
 		w.FromToken,
 		ac.Account.Address.Hex(),
-		result.String(),
+		received,
 		w.ToToken,
+		w.toToken.Format(result),
 		tx.Hash().Hex())
 	return true, nil
 }
@@ -0,0 +1,145 @@
+package activity
+
+import (
+	"activity-bot/pkg/abi/weth9"
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/random"
+	"activity-bot/pkg/token"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+)
+
+// WrapNative wraps a share of the native balance into the chain's wrapped native token, such as WAVAX, or
+// unwraps a share of the wrapped balance back.
+type WrapNative struct {
+	chain         string
+	Unwrap        bool             // Withdraws the wrapped token instead of depositing the native token
+	ShareSupplier *random.Supplier // Share of the balance in basis points
+	GasReserve    string           // Decimal native amount left untouched to pay for gas, such as "0.05"
+	weth9         *weth9.Weth9
+	native        *token.Token // Computed on can execute
+	value         *big.Int     // Computed on can execute
+}
+
+func NewWrapNative(chain string, shareSupplier *random.Supplier, gasReserve string) *WrapNative {
+	return &WrapNative{
+		chain:         chain,
+		ShareSupplier: shareSupplier,
+		GasReserve:    gasReserve,
+	}
+}
+
+func NewUnwrapNative(chain string, shareSupplier *random.Supplier, gasReserve string) *WrapNative {
+	w := NewWrapNative(chain, shareSupplier, gasReserve)
+	w.Unwrap = true
+	return w
+}
+
+func (w *WrapNative) Chain() string {
+	return w.chain
+}
+
+func (w *WrapNative) Contracts(c *chain.Chain) ([]common.Address, error) {
+	wrappedNative, err := c.WrappedNative()
+	if err != nil {
+		return nil, err
+	}
+	return []common.Address{wrappedNative}, nil
+}
+
+func (w *WrapNative) CanExecute(ac ActivityContext) (bool, error) {
+	if w.ShareSupplier.Min().Sign() <= 0 || w.ShareSupplier.Max().Cmp(big.NewInt(10000)) > 0 {
+		return false, errors.New("WrapNative share must be within (0, 10000] bps")
+	}
+	w.native = token.Native(ac.Chain)
+	gasReserve, err := w.native.Parse(w.GasReserve)
+	if err != nil {
+		return false, err
+	}
+	wrappedNative, err := ac.Chain.WrappedNative()
+	if err != nil {
+		return false, err
+	}
+	log.Printf("Creating W%s contract instance\n", ac.Chain.NativeSymbol)
+	contract, err := weth9.NewWeth9(wrappedNative, ac.Client)
+	if err != nil {
+		return false, err
+	}
+	w.weth9 = contract
+
+	nativeBalance, err := ac.Client.BalanceAt(ac.Context, ac.Account.Address, nil)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Error getting account balance [%s]: %v", ac.Account.Address.Hex(), err))
+	}
+	if nativeBalance.Cmp(gasReserve) < 0 {
+		return false, errors.New(fmt.Sprintf("Account [%s] has less %s than the gas reserve of %s", ac.Account.Address.Hex(), ac.Chain.NativeSymbol, w.GasReserve))
+	}
+
+	var wrappedBalance *big.Int
+	if w.Unwrap {
+		wrappedBalance, err = w.weth9.BalanceOf(ac.CallOpts(), ac.Account.Address)
+		if err != nil {
+			return false, errors.New(fmt.Sprintf("Error getting account W%s balance [%s]: %v", ac.Chain.NativeSymbol, ac.Account.Address.Hex(), err))
+		}
+	}
+
+	w.value = wrapValue(w.Unwrap, nativeBalance, gasReserve, wrappedBalance, w.ShareSupplier.Supply())
+	if w.value.Sign() <= 0 {
+		return false, errors.New(fmt.Sprintf("Account [%s] has nothing to %s", ac.Account.Address.Hex(), w.action()))
+	}
+
+	return true, nil
+}
+
+func (w *WrapNative) Execute(ac ActivityContext) (bool, error) {
+	ac.Transactor.Context = ac.Context
+	log.Printf("[%s] started to %s %s %s\n", ac.Account.Address.Hex(), w.action(), w.native.Format(w.value), ac.Chain.NativeSymbol)
+
+	var tx *types.Transaction
+	var err error
+	ac.Transactor.GasLimit = 0 // Estimated
+	if w.Unwrap {
+		tx, err = w.weth9.Withdraw(ac.Transactor, w.value)
+	} else {
+		ac.Transactor.Value = w.value
+		tx, err = w.weth9.Deposit(ac.Transactor)
+		ac.Transactor.Value = big.NewInt(0)
+	}
+	if err != nil {
+		return false, err
+	}
+	log.Printf("W%s %s tx sent: %s", ac.Chain.NativeSymbol, w.action(), tx.Hash().Hex())
+	receipt, err := ac.WaitForReceipt(tx)
+	if err != nil {
+		return false, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return false, errors.New(fmt.Sprintf("W%s %s tx failed: %s", ac.Chain.NativeSymbol, w.action(), receipt.TxHash.Hex()))
+	}
+	return true, nil
+}
+
+// wrapValue returns the share in basis points of the native balance above the gas reserve to wrap, or of the
+// wrapped balance to unwrap.
+func wrapValue(unwrap bool, nativeBalance *big.Int, gasReserve *big.Int, wrappedBalance *big.Int, shareBps *big.Int) *big.Int {
+	available := new(big.Int).Sub(nativeBalance, gasReserve)
+	if unwrap {
+		available = wrappedBalance
+	}
+	if available.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+	value := new(big.Int).Mul(available, shareBps)
+	return value.Div(value, big.NewInt(10000))
+}
+
+func (w *WrapNative) action() string {
+	if w.Unwrap {
+		return "unwrap"
+	}
+	return "wrap"
+}
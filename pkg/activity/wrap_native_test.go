@@ -0,0 +1,33 @@
+package activity
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestWrapValue(t *testing.T) {
+	tests := []struct {
+		name           string
+		unwrap         bool
+		nativeBalance  int64
+		gasReserve     int64
+		wrappedBalance *big.Int
+		shareBps       int64
+		want           int64
+	}{
+		{"share above the gas reserve", false, 1100, 100, nil, 5000, 500},
+		{"whole balance above the gas reserve", false, 1100, 100, nil, 10000, 1000},
+		{"balance at the gas reserve", false, 100, 100, nil, 10000, 0},
+		{"rounds down", false, 1099, 100, nil, 1, 0},
+		{"unwrap ignores the gas reserve", true, 100, 100, big.NewInt(2000), 2500, 500},
+		{"nothing wrapped", true, 1000, 100, big.NewInt(0), 10000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapValue(tt.unwrap, big.NewInt(tt.nativeBalance), big.NewInt(tt.gasReserve), tt.wrappedBalance, big.NewInt(tt.shareBps))
+			if got.Int64() != tt.want {
+				t.Errorf("wrapValue() = %v, want %d", got, tt.want)
+			}
+		})
+	}
+}
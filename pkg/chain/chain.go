@@ -0,0 +1,117 @@
+package chain
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"time"
+)
+
+// NativeToken is the placeholder address used by DEX routers for the chain's native token.
+var NativeToken = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+
+// Names of the built-in chains.
+const (
+	Ethereum  = "ethereum"
+	Avalanche = "avalanche"
+	Fantom    = "fantom"
+	Polygon   = "polygon"
+	Arbitrum  = "arbitrum"
+	Optimism  = "optimism"
+	Bsc       = "bsc"
+)
+
+// Names of the contracts in the contract book.
+const (
+	StargateRouter    = "stargateRouter"
+	StargateRouterEth = "stargateRouterEth"
+	BitcoinBridge     = "bitcoinBridge"
+	WooRouter         = "wooRouter"
+	TraderJoeRouter   = "traderJoeRouter"
+)
+
+// Duration is a time.Duration written as a string such as "2s" in configuration files.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// RateLimit limits the requests sent to each rpc endpoint of a chain, a zero rate disables it.
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	Burst             int     `json:"burst"`
+}
+
+// Retry configures how failed rpc requests are retried, zero values use the client defaults.
+type Retry struct {
+	MaxAttempts    int      `json:"maxAttempts"`
+	InitialBackoff Duration `json:"initialBackoff"`
+	MaxBackoff     Duration `json:"maxBackoff"`
+}
+
+type Chain struct {
+	Name             string                    `json:"name"`
+	ChainId          uint64                    `json:"chainId"`
+	LayerZeroChainId uint16                    `json:"layerZeroChainId"` // https://stargateprotocol.gitbook.io/stargate/developers/chain-ids
+	RpcUrls          []string                  `json:"rpcUrls"`
+	WsUrls           []string                  `json:"wsUrls"`
+	NativeSymbol     string                    `json:"nativeSymbol"`
+	BlockTime        Duration                  `json:"blockTime"`
+	Confirmations    uint64                    `json:"confirmations"`
+	ReceiptTimeout   Duration                  `json:"receiptTimeout"`
+	RateLimit        RateLimit                 `json:"rateLimit"`
+	Retry            Retry                     `json:"retry"`
+	Tokens           map[string]common.Address `json:"tokens"`        // Token addresses by symbol
+	Contracts        map[string]common.Address `json:"contracts"`     // Contract addresses by contract book name
+	StargatePools    map[string]uint64         `json:"stargatePools"` // https://stargateprotocol.gitbook.io/stargate/developers/pool-ids
+}
+
+// EvmChainId returns the EVM chain id as expected by transactors.
+func (c *Chain) EvmChainId() *big.Int {
+	return new(big.Int).SetUint64(c.ChainId)
+}
+
+func (c *Chain) Token(symbol string) (common.Address, error) {
+	address, ok := c.Tokens[symbol]
+	if !ok {
+		return common.Address{}, fmt.Errorf("token %s is not configured on chain %s", symbol, c.Name)
+	}
+	return address, nil
+}
+
+func (c *Chain) Contract(name string) (common.Address, error) {
+	address, ok := c.Contracts[name]
+	if !ok {
+		return common.Address{}, fmt.Errorf("contract %s is not configured on chain %s", name, c.Name)
+	}
+	return address, nil
+}
+
+func (c *Chain) StargatePool(symbol string) (*big.Int, error) {
+	poolId, ok := c.StargatePools[symbol]
+	if !ok {
+		return nil, fmt.Errorf("no Stargate pool for %s on chain %s", symbol, c.Name)
+	}
+	return new(big.Int).SetUint64(poolId), nil
+}
+
+// WrappedNative returns the WETH9-style token wrapping the native token, listed in the token book as "W" followed
+// by the native symbol, such as WAVAX.
+func (c *Chain) WrappedNative() (common.Address, error) {
+	return c.Token("W" + c.NativeSymbol)
+}
@@ -0,0 +1,183 @@
+package chain
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"time"
+)
+
+func defaultChains() []*Chain {
+	return []*Chain{
+		{
+			Name:             Ethereum,
+			ChainId:          1,
+			LayerZeroChainId: 101,
+			RpcUrls:          []string{"https://mainnet.infura.io/v3/"},
+			WsUrls:           []string{"wss://mainnet.infura.io/ws/v3/"},
+			NativeSymbol:     "ETH",
+			BlockTime:        Duration(12 * time.Second),
+			Confirmations:    2,
+			ReceiptTimeout:   Duration(3 * time.Minute),
+			RateLimit:        RateLimit{RequestsPerSecond: 10, Burst: 20},
+			Tokens: map[string]common.Address{
+				"WETH": common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"),
+				"USDC": common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"),
+				"USDT": common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7"),
+			},
+			Contracts: map[string]common.Address{
+				StargateRouter:    common.HexToAddress("0x8731d54E9D02c286767d56ac03e8037C07e01e98"),
+				StargateRouterEth: common.HexToAddress("0x150f94B44927F078737562f0fcF3C95c01Cc2376"),
+			},
+			StargatePools: map[string]uint64{
+				"USDC": 1,
+				"USDT": 2,
+				"ETH":  13,
+			},
+		},
+		{
+			Name:             Avalanche,
+			ChainId:          43114,
+			LayerZeroChainId: 106,
+			RpcUrls:          []string{"https://avalanche-mainnet.infura.io/v3/"},
+			WsUrls:           []string{"wss://avalanche-mainnet.infura.io/ws/v3/"},
+			NativeSymbol:     "AVAX",
+			BlockTime:        Duration(2 * time.Second),
+			Confirmations:    1,
+			ReceiptTimeout:   Duration(30 * time.Second),
+			RateLimit:        RateLimit{RequestsPerSecond: 10, Burst: 20},
+			Tokens: map[string]common.Address{
+				"WAVAX":  common.HexToAddress("0xB31f66AA3C1e785363F0875A1B74E27b85FD66c7"),
+				"USDC":   common.HexToAddress("0xB97EF9Ef8734C71904D8002F8b6Bc66Dd9c48a6E"),
+				"USDC.e": common.HexToAddress("0xA7D7079b0FEaD91F3e65f86E8915Cb59c1a4C664"),
+				"BTC.b":  common.HexToAddress("0x152b9d0FdC40C096757F570A51E494bd4b943E50"),
+				"USDT":   common.HexToAddress("0x9702230A8Ea53601f5cD2dc00fDBc13d4dF4A8c7"),
+			},
+			Contracts: map[string]common.Address{
+				StargateRouter:  common.HexToAddress("0x45A01E4e04F14f7A4a6702c74187c5F6222033cd"),
+				BitcoinBridge:   common.HexToAddress("0x2297aEbD383787A160DD0d9F71508148769342E3"),
+				WooRouter:       common.HexToAddress("0xC22FBb3133dF781E6C25ea6acebe2D2Bb8CeA2f9"),
+				TraderJoeRouter: common.HexToAddress("0xb4315e873dBcf96Ffd0acd8EA43f689D8c20fB30"),
+			},
+			StargatePools: map[string]uint64{
+				"USDC": 1,
+				"USDT": 2,
+			},
+		},
+		{
+			Name:             Fantom,
+			ChainId:          250,
+			LayerZeroChainId: 112,
+			RpcUrls:          []string{"https://rpc.ftm.tools/"},
+			WsUrls:           []string{"wss://wsapi.fantom.network/"},
+			NativeSymbol:     "FTM",
+			BlockTime:        Duration(time.Second),
+			Confirmations:    1,
+			ReceiptTimeout:   Duration(30 * time.Second),
+			RateLimit:        RateLimit{RequestsPerSecond: 10, Burst: 20},
+			Tokens: map[string]common.Address{
+				"WFTM": common.HexToAddress("0x21be370D5312f44cB42ce377BC9b8a0cEF1A4C83"),
+				"USDC": common.HexToAddress("0x04068DA6C83AFCFA0e13ba15A6696662335D5B75"),
+			},
+			Contracts: map[string]common.Address{
+				StargateRouter: common.HexToAddress("0xAf5191B0De278C7286d6C7CC6ab6BB8A73bA2Cd6"),
+			},
+			StargatePools: map[string]uint64{
+				"USDC": 21,
+			},
+		},
+		{
+			Name:             Polygon,
+			ChainId:          137,
+			LayerZeroChainId: 109,
+			RpcUrls:          []string{"https://polygon-mainnet.infura.io/v3/"},
+			WsUrls:           []string{"wss://polygon-mainnet.infura.io/ws/v3/"},
+			NativeSymbol:     "MATIC",
+			BlockTime:        Duration(2 * time.Second),
+			Confirmations:    32,
+			ReceiptTimeout:   Duration(2 * time.Minute),
+			RateLimit:        RateLimit{RequestsPerSecond: 10, Burst: 20},
+			Tokens: map[string]common.Address{
+				"WMATIC": common.HexToAddress("0x0d500B1d8E8eF31E21C99d1Db9A6444d3ADf1270"),
+				"USDC":   common.HexToAddress("0x2791Bca1f2de4661ED88A30C99A7a9449Aa84174"),
+				"BTC.b":  common.HexToAddress("0x2297aEbD383787A160DD0d9F71508148769342E3"),
+				"USDT":   common.HexToAddress("0xc2132D05D31c914a87C6611C10748AEb04B58e8F"),
+			},
+			Contracts: map[string]common.Address{
+				StargateRouter: common.HexToAddress("0x45A01E4e04F14f7A4a6702c74187c5F6222033cd"),
+				BitcoinBridge:  common.HexToAddress("0x2297aEbD383787A160DD0d9F71508148769342E3"),
+			},
+			StargatePools: map[string]uint64{
+				"USDC": 1,
+				"USDT": 2,
+			},
+		},
+		{
+			Name:             Arbitrum,
+			ChainId:          42161,
+			LayerZeroChainId: 110,
+			RpcUrls:          []string{"https://arb1.arbitrum.io/rpc"},
+			NativeSymbol:     "ETH",
+			BlockTime:        Duration(250 * time.Millisecond),
+			Confirmations:    1,
+			ReceiptTimeout:   Duration(30 * time.Second),
+			RateLimit:        RateLimit{RequestsPerSecond: 10, Burst: 20},
+			Tokens: map[string]common.Address{
+				"WETH": common.HexToAddress("0x82aF49447D8a07e3bd95BD0d56f35241523fBab1"),
+				"USDC": common.HexToAddress("0xFF970A61A04b1cA14834A43f5dE4533eBDDB5CC8"),
+				"USDT": common.HexToAddress("0xFd086bC7CD5C481DCC9C85ebE478A1C0b69FCbb9"),
+			},
+			Contracts: map[string]common.Address{
+				StargateRouter:    common.HexToAddress("0x53Bf833A5d6c4ddA888F69c22C88C9f356a41614"),
+				StargateRouterEth: common.HexToAddress("0xbf22f0f184bCcbeA268dF387a49fF5238dD23E40"),
+			},
+			StargatePools: map[string]uint64{
+				"USDC": 1,
+				"USDT": 2,
+				"ETH":  13,
+			},
+		},
+		{
+			Name:             Optimism,
+			ChainId:          10,
+			LayerZeroChainId: 111,
+			RpcUrls:          []string{"https://mainnet.optimism.io"},
+			NativeSymbol:     "ETH",
+			BlockTime:        Duration(2 * time.Second),
+			Confirmations:    1,
+			ReceiptTimeout:   Duration(30 * time.Second),
+			RateLimit:        RateLimit{RequestsPerSecond: 10, Burst: 20},
+			Tokens: map[string]common.Address{
+				"WETH": common.HexToAddress("0x4200000000000000000000000000000000000006"),
+				"USDC": common.HexToAddress("0x7F5c764cBc14f9669B88837ca1490cCa17c31607"),
+			},
+			Contracts: map[string]common.Address{
+				StargateRouter:    common.HexToAddress("0xB0D502E938ed5f4df2E681fE6E419ff29631d62b"),
+				StargateRouterEth: common.HexToAddress("0xB49c4e680174E331CB0A7fF3Ab58afC9738d5F8b"),
+			},
+			StargatePools: map[string]uint64{
+				"USDC": 1,
+				"ETH":  13,
+			},
+		},
+		{
+			Name:             Bsc,
+			ChainId:          56,
+			LayerZeroChainId: 102,
+			RpcUrls:          []string{"https://bsc-dataseed.binance.org/"},
+			NativeSymbol:     "BNB",
+			BlockTime:        Duration(3 * time.Second),
+			Confirmations:    3,
+			ReceiptTimeout:   Duration(time.Minute),
+			RateLimit:        RateLimit{RequestsPerSecond: 10, Burst: 20},
+			Tokens: map[string]common.Address{
+				"WBNB": common.HexToAddress("0xbb4CdB9CBd36B01bD1cBaEBF2De08d9173bc095c"),
+				"USDT": common.HexToAddress("0x55d398326f99059fF775485246999027B3197955"),
+			},
+			Contracts: map[string]common.Address{
+				StargateRouter: common.HexToAddress("0x4a364f8c717cAAD9A442737Eb7b8A55cc6cf18D8"),
+			},
+			StargatePools: map[string]uint64{
+				"USDT": 2,
+			},
+		},
+	}
+}
@@ -0,0 +1,99 @@
+package chain
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Registry holds the configuration of every chain the bot can run on.
+type Registry struct {
+	chains map[string]*Chain
+}
+
+type registryFile struct {
+	Chains map[string]json.RawMessage `json:"chains"`
+}
+
+func NewRegistry(chains ...*Chain) *Registry {
+	r := &Registry{chains: make(map[string]*Chain, len(chains))}
+	for _, c := range chains {
+		r.chains[c.Name] = c
+	}
+	return r
+}
+
+// DefaultRegistry returns a registry with the built-in chains.
+func DefaultRegistry() *Registry {
+	return NewRegistry(defaultChains()...)
+}
+
+// LoadRegistry returns the built-in chains overridden by the configuration file at path. Chains of the
+// file are keyed by name, fields present in the file replace the defaults while address books and pools
+// are merged, so a file only needs to hold what differs. Unknown chain names add new chains.
+func LoadRegistry(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file registryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid chain configuration %s: %w", path, err)
+	}
+
+	r := DefaultRegistry()
+	for name, raw := range file.Chains {
+		c, ok := r.chains[name]
+		if !ok {
+			c = &Chain{}
+		}
+		if err := json.Unmarshal(raw, c); err != nil {
+			return nil, fmt.Errorf("invalid configuration of chain %s: %w", name, err)
+		}
+		c.Name = name
+		if c.ChainId == 0 {
+			return nil, fmt.Errorf("chain %s has no chain id", name)
+		}
+		r.chains[name] = c
+	}
+	return r, nil
+}
+
+func (r *Registry) Get(name string) (*Chain, error) {
+	c, ok := r.chains[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown chain: %s", name)
+	}
+	return c, nil
+}
+
+func (r *Registry) ByChainId(chainId uint64) (*Chain, error) {
+	for _, c := range r.chains {
+		if c.ChainId == chainId {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("no chain with chain id %d", chainId)
+}
+
+func (r *Registry) ByLayerZeroChainId(chainId uint16) (*Chain, error) {
+	for _, c := range r.chains {
+		if c.LayerZeroChainId == chainId {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("no chain with LayerZero chain id %d", chainId)
+}
+
+// Chains returns every chain sorted by name.
+func (r *Registry) Chains() []*Chain {
+	chains := make([]*Chain, 0, len(r.chains))
+	for _, c := range r.chains {
+		chains = append(chains, c)
+	}
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].Name < chains[j].Name
+	})
+	return chains
+}
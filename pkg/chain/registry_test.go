@@ -0,0 +1,83 @@
+package chain
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "chains.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRegistryOverridesDefaults(t *testing.T) {
+	path := writeConfig(t, `{
+		"chains": {
+			"avalanche": {
+				"rpcUrls": ["https://example.com/avax"],
+				"blockTime": "3s",
+				"tokens": {"USDT": "0x9702230A8Ea53601f5cD2dc00fDBc13d4dF4A8c7"}
+			},
+			"arbitrum": {"chainId": 42161, "layerZeroChainId": 110}
+		}
+	}`)
+	r, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	avalanche, err := r.Get(Avalanche)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(avalanche.RpcUrls) != 1 || avalanche.RpcUrls[0] != "https://example.com/avax" {
+		t.Errorf("RpcUrls = %v, want the configured url", avalanche.RpcUrls)
+	}
+	if time.Duration(avalanche.BlockTime) != 3*time.Second {
+		t.Errorf("BlockTime = %v, want 3s", time.Duration(avalanche.BlockTime))
+	}
+	if avalanche.ChainId != 43114 {
+		t.Errorf("ChainId = %d, want the default 43114", avalanche.ChainId)
+	}
+	// Token books are merged with the defaults
+	if usdt, err := avalanche.Token("USDT"); err != nil || usdt != common.HexToAddress("0x9702230A8Ea53601f5cD2dc00fDBc13d4dF4A8c7") {
+		t.Errorf("Token(USDT) = %v, %v", usdt, err)
+	}
+	if _, err := avalanche.Token("BTC.b"); err != nil {
+		t.Errorf("Token(BTC.b) = %v, want the default address", err)
+	}
+
+	arbitrum, err := r.ByLayerZeroChainId(110)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arbitrum.Name != "arbitrum" || arbitrum.ChainId != 42161 {
+		t.Errorf("ByLayerZeroChainId(110) = %s/%d, want arbitrum/42161", arbitrum.Name, arbitrum.ChainId)
+	}
+
+	// Overrides must not leak into other registries
+	if c, _ := DefaultRegistry().Get(Avalanche); time.Duration(c.BlockTime) != 2*time.Second {
+		t.Errorf("default registry was modified by the override")
+	}
+}
+
+func TestLoadRegistryRejectsChainWithoutId(t *testing.T) {
+	path := writeConfig(t, `{"chains": {"unknown": {"rpcUrls": ["https://example.com"]}}}`)
+	if _, err := LoadRegistry(path); err == nil {
+		t.Error("LoadRegistry() error = nil, want an error for a chain without chain id")
+	}
+}
+
+func TestDefaultChainsHaveWrappedNative(t *testing.T) {
+	for _, c := range DefaultRegistry().Chains() {
+		if _, err := c.WrappedNative(); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+package client
+
+import (
+	"activity-bot/pkg/chain"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHealthCheckInterval = 15 * time.Second
+	// DefaultMaxHeadLag is how many blocks an endpoint may be behind the best known head before it is unhealthy
+	DefaultMaxHeadLag  = 5
+	healthCheckTimeout = 5 * time.Second
+)
+
+var _ bind.ContractBackend = (*Client)(nil)
+
+// Options configure how a client uses its endpoints.
+type Options struct {
+	MaxHeadLag        uint64
+	RequestsPerSecond float64 // Per endpoint, zero disables rate limiting
+	Burst             int
+	Retry             RetryPolicy
+}
+
+var DefaultOptions = Options{
+	MaxHeadLag: DefaultMaxHeadLag,
+	Retry:      DefaultRetryPolicy,
+}
+
+// OptionsFor returns the options configured for the chain, using defaults for what is not set.
+func OptionsFor(c *chain.Chain) Options {
+	options := DefaultOptions
+	options.RequestsPerSecond = c.RateLimit.RequestsPerSecond
+	options.Burst = c.RateLimit.Burst
+	if c.Retry.MaxAttempts > 0 {
+		options.Retry.MaxAttempts = c.Retry.MaxAttempts
+	}
+	if c.Retry.InitialBackoff > 0 {
+		options.Retry.InitialBackoff = time.Duration(c.Retry.InitialBackoff)
+	}
+	if c.Retry.MaxBackoff > 0 {
+		options.Retry.MaxBackoff = time.Duration(c.Retry.MaxBackoff)
+	}
+	return options
+}
+
+type endpoint struct {
+	url     string
+	rpc     *rpc.Client
+	eth     *ethclient.Client
+	limiter *RateLimiter
+	healthy bool
+	head    uint64
+}
+
+// Client is an Ethereum client over several endpoints of the same chain. Reads are routed to healthy
+// endpoints and fail over to the next one on endpoint errors, writes go to the first endpoint, the
+// primary, and fall back to the others. Endpoints failing or lagging behind the best head are marked
+// unhealthy until a health check sees them recover, rate limiting endpoints stay healthy. Requests are
+// rate limited per endpoint and retried with backoff when they fail with a retryable error on every
+// endpoint.
+type Client struct {
+	endpoints  []*endpoint
+	maxHeadLag uint64
+	retry      RetryPolicy
+	next       int // Endpoint the next read starts from, spreads reads across healthy endpoints
+	lock       *sync.Mutex
+}
+
+// Dial connects to every url with the default options, see DialWithOptions.
+func Dial(ctx context.Context, urls ...string) (*Client, error) {
+	return DialWithOptions(ctx, DefaultOptions, urls...)
+}
+
+// DialChain connects to the rpc endpoints of the chain with its options.
+func DialChain(ctx context.Context, c *chain.Chain) (*Client, error) {
+	return DialWithOptions(ctx, OptionsFor(c), c.RpcUrls...)
+}
+
+// DialWithOptions connects to every url, the first one being the primary. Endpoints that cannot be
+// dialed are skipped, dialing fails only if none of them can be reached.
+func DialWithOptions(ctx context.Context, options Options, urls ...string) (*Client, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no rpc endpoint given")
+	}
+	c := &Client{
+		endpoints:  make([]*endpoint, 0, len(urls)),
+		maxHeadLag: options.MaxHeadLag,
+		retry:      options.Retry,
+		lock:       &sync.Mutex{},
+	}
+	for _, url := range urls {
+		rpcClient, err := rpc.DialContext(ctx, url)
+		if err != nil {
+			log.Printf("Unable to dial rpc endpoint %s: %v\n", url, err)
+			continue
+		}
+		c.endpoints = append(c.endpoints, &endpoint{
+			url:     url,
+			rpc:     rpcClient,
+			eth:     ethclient.NewClient(rpcClient),
+			limiter: NewRateLimiter(options.RequestsPerSecond, options.Burst),
+			healthy: true,
+		})
+	}
+	if len(c.endpoints) == 0 {
+		return nil, fmt.Errorf("unable to dial any of the rpc endpoints %v", urls)
+	}
+	return c, nil
+}
+
+func (c *Client) Close() {
+	for _, e := range c.endpoints {
+		e.rpc.Close()
+	}
+}
+
+// StartHealthChecks checks every endpoint at the given interval until the context is done.
+func (c *Client) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	go func() {
+		for {
+			c.CheckHealth(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// CheckHealth fetches the head of every endpoint, endpoints that fail or lag behind the best head
+// by more than the allowed lag are marked unhealthy.
+func (c *Client) CheckHealth(ctx context.Context) {
+	heads := make([]uint64, len(c.endpoints))
+	errs := make([]error, len(c.endpoints))
+	wg := sync.WaitGroup{}
+	for i, e := range c.endpoints {
+		wg.Add(1)
+		go func(i int, e *endpoint) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+			if errs[i] = e.limiter.Wait(checkCtx, 1); errs[i] != nil {
+				return
+			}
+			heads[i], errs[i] = e.eth.BlockNumber(checkCtx)
+		}(i, e)
+	}
+	wg.Wait()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var bestHead uint64
+	for i := range c.endpoints {
+		if errs[i] == nil && heads[i] > bestHead {
+			bestHead = heads[i]
+		}
+	}
+	for i, e := range c.endpoints {
+		healthy := errs[i] == nil && heads[i]+c.maxHeadLag >= bestHead
+		if healthy != e.healthy {
+			if healthy {
+				log.Printf("Rpc endpoint %s is healthy again at block %d\n", e.url, heads[i])
+			} else if errs[i] != nil {
+				log.Printf("Rpc endpoint %s is unhealthy: %v\n", e.url, errs[i])
+			} else {
+				log.Printf("Rpc endpoint %s is unhealthy: head %d lags behind %d\n", e.url, heads[i], bestHead)
+			}
+		}
+		e.healthy = healthy
+		if errs[i] == nil {
+			e.head = heads[i]
+		}
+	}
+}
+
+// readOrder returns the healthy endpoints starting from the next one in turn, followed by the
+// unhealthy ones as a last resort.
+func (c *Client) readOrder() []*endpoint {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	start := c.next
+	c.next = (c.next + 1) % len(c.endpoints)
+	return c.order(start)
+}
+
+// writeOrder returns the healthy endpoints starting from the primary, followed by the unhealthy ones.
+func (c *Client) writeOrder() []*endpoint {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order(0)
+}
+
+func (c *Client) order(start int) []*endpoint {
+	healthy := make([]*endpoint, 0, len(c.endpoints))
+	unhealthy := make([]*endpoint, 0)
+	for i := range c.endpoints {
+		e := c.endpoints[(start+i)%len(c.endpoints)]
+		if e.healthy {
+			healthy = append(healthy, e)
+		} else {
+			unhealthy = append(unhealthy, e)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+func (c *Client) markUnhealthy(e *endpoint, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e.healthy {
+		log.Printf("Rpc endpoint %s is unhealthy: %v\n", e.url, err)
+	}
+	e.healthy = false
+}
+
+// isEndpointError tells whether the error is caused by the endpoint rather than by the request, in
+// which case the request may succeed on another endpoint.
+func isEndpointError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return true
+	}
+	// The endpoint answered with a JSON-RPC error such as a revert, other endpoints would answer the same
+	var rpcErr rpc.Error
+	return !errors.As(err, &rpcErr)
+}
+
+// isRateLimited tells whether the endpoint rejected the request with HTTP 429 Too Many Requests.
+func isRateLimited(err error) bool {
+	var httpErr rpc.HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests
+}
+
+// call runs fn on the endpoints in order until one succeeds or fails with an error that is not caused
+// by the endpoint. If the last error is retryable, it waits for the backoff and goes through the
+// endpoints again, up to the maximum number of attempts. Each request costs the given number of
+// rate limiter tokens.
+func call[T any](c *Client, ctx context.Context, order func() []*endpoint, cost int, fn func(e *endpoint) (T, error)) (T, error) {
+	var result T
+	var err error
+	for attempt := 0; ; attempt++ {
+		for _, e := range order() {
+			if err = e.limiter.Wait(ctx, cost); err != nil {
+				return result, err
+			}
+			result, err = fn(e)
+			if err == nil || !isEndpointError(err) || ctx.Err() != nil {
+				break
+			}
+			// A rate limiting endpoint is healthy, the request is retried after the backoff if no other endpoint answers
+			if !isRateLimited(err) {
+				c.markUnhealthy(e, err)
+			}
+		}
+		if err == nil || ctx.Err() != nil || !IsRetryable(err) || attempt+1 >= c.retry.MaxAttempts {
+			return result, err
+		}
+
+		backoff := c.retry.Backoff(attempt)
+		log.Printf("Rpc request failed, retrying in %v: %v\n", backoff, err)
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func read[T any](c *Client, ctx context.Context, fn func(eth *ethclient.Client) (T, error)) (T, error) {
+	return call(c, ctx, c.readOrder, 1, func(e *endpoint) (T, error) {
+		return fn(e.eth)
+	})
+}
+
+func (c *Client) ChainID(ctx context.Context) (*big.Int, error) {
+	return read(c, ctx, func(eth *ethclient.Client) (*big.Int, error) {
+		return eth.ChainID(ctx)
+	})
+}
+
+func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	return read(c, ctx, func(eth *ethclient.Client) (uint64, error) {
+		return eth.BlockNumber(ctx)
+	})
+}
+
+func (c *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return read(c, ctx, func(eth *ethclient.Client) (*types.Header, error) {
+		return eth.HeaderByNumber(ctx, number)
+	})
+}
+
+func (c *Client) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return read(c, ctx, func(eth *ethclient.Client) (*types.Block, error) {
+		return eth.BlockByHash(ctx, hash)
+	})
+}
+
+func (c *Client) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return read(c, ctx, func(eth *ethclient.Client) (*types.Block, error) {
+		return eth.BlockByNumber(ctx, number)
+	})
+}
+
+func (c *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return read(c, ctx, func(eth *ethclient.Client) (*types.Receipt, error) {
+		return eth.TransactionReceipt(ctx, txHash)
+	})
+}
+
+func (c *Client) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return read(c, ctx, func(eth *ethclient.Client) (*big.Int, error) {
+		return eth.BalanceAt(ctx, account, blockNumber)
+	})
+}
+
+func (c *Client) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return read(c, ctx, func(eth *ethclient.Client) ([]byte, error) {
+		return eth.CodeAt(ctx, account, blockNumber)
+	})
+}
+
+func (c *Client) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return read(c, ctx, func(eth *ethclient.Client) (uint64, error) {
+		return eth.NonceAt(ctx, account, blockNumber)
+	})
+}
+
+func (c *Client) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return read(c, ctx, func(eth *ethclient.Client) ([]byte, error) {
+		return eth.CallContract(ctx, msg, blockNumber)
+	})
+}
+
+func (c *Client) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return read(c, ctx, func(eth *ethclient.Client) ([]types.Log, error) {
+		return eth.FilterLogs(ctx, q)
+	})
+}
+
+func (c *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return read(c, ctx, func(eth *ethclient.Client) (*big.Int, error) {
+		return eth.SuggestGasPrice(ctx)
+	})
+}
+
+func (c *Client) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return read(c, ctx, func(eth *ethclient.Client) (*big.Int, error) {
+		return eth.SuggestGasTipCap(ctx)
+	})
+}
+
+func (c *Client) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return read(c, ctx, func(eth *ethclient.Client) (uint64, error) {
+		return eth.EstimateGas(ctx, msg)
+	})
+}
+
+// The pending state is specific to the node the transactions were sent to, so pending reads
+// follow the write order.
+
+func (c *Client) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return call(c, ctx, c.writeOrder, 1, func(e *endpoint) ([]byte, error) {
+		return e.eth.PendingCodeAt(ctx, account)
+	})
+}
+
+func (c *Client) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return call(c, ctx, c.writeOrder, 1, func(e *endpoint) (uint64, error) {
+		return e.eth.PendingNonceAt(ctx, account)
+	})
+}
+
+// SendTransaction sends the signed transaction. A previous attempt may have reached a node before failing,
+// the same transaction sent again is then rejected as already known, or once mined as nonce too low or
+// underpriced. The transaction counts as sent in that case, provided the endpoint knows its hash.
+func (c *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	attempts := 0
+	_, err := call(c, ctx, c.writeOrder, 1, func(e *endpoint) (struct{}, error) {
+		attempts++
+		err := e.eth.SendTransaction(ctx, tx)
+		if err != nil && attempts > 1 {
+			message := err.Error()
+			if strings.Contains(message, "already known") {
+				return struct{}{}, nil
+			}
+			if strings.Contains(message, "nonce too low") || strings.Contains(message, "replacement transaction underpriced") {
+				if _, _, lookupErr := e.eth.TransactionByHash(ctx, tx.Hash()); lookupErr == nil {
+					return struct{}{}, nil
+				}
+			}
+		}
+		return struct{}{}, err
+	})
+	return err
+}
+
+// BatchCallContext sends all requests in a single JSON-RPC batch to one endpoint.
+func (c *Client) BatchCallContext(ctx context.Context, batch []rpc.BatchElem) error {
+	_, err := call(c, ctx, c.readOrder, len(batch), func(e *endpoint) (struct{}, error) {
+		return struct{}{}, e.rpc.BatchCallContext(ctx, batch)
+	})
+	return err
+}
+
+// Subscriptions are made on the first endpoint supporting them, usually a websocket one.
+
+func (c *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return subscribe(c, ctx, func(eth *ethclient.Client) (ethereum.Subscription, error) {
+		return eth.SubscribeNewHead(ctx, ch)
+	})
+}
+
+func (c *Client) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return subscribe(c, ctx, func(eth *ethclient.Client) (ethereum.Subscription, error) {
+		return eth.SubscribeFilterLogs(ctx, q, ch)
+	})
+}
+
+func subscribe(c *Client, ctx context.Context, fn func(eth *ethclient.Client) (ethereum.Subscription, error)) (ethereum.Subscription, error) {
+	var err error
+	for _, e := range c.writeOrder() {
+		var sub ethereum.Subscription
+		sub, err = fn(e.eth)
+		if err == nil || ctx.Err() != nil {
+			return sub, err
+		}
+		if !errors.Is(err, rpc.ErrNotificationsUnsupported) {
+			c.markUnhealthy(e, err)
+		}
+	}
+	return nil, err
+}
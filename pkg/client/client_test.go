@@ -0,0 +1,226 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeEth struct {
+	chainId int64
+	head    uint64
+	nonce   uint64                             // Next nonce of the sender
+	sent    map[common.Hash]*types.Transaction // Transactions sent to the node, mined at once
+}
+
+func (f *fakeEth) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(f.chainId))
+}
+
+func (f *fakeEth) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(f.head)
+}
+
+func (f *fakeEth) SendRawTransaction(data hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(data); err != nil {
+		return common.Hash{}, err
+	}
+	if tx.Nonce() < f.nonce {
+		return common.Hash{}, errors.New("nonce too low")
+	}
+	if f.sent == nil {
+		f.sent = make(map[common.Hash]*types.Transaction)
+	}
+	f.sent[tx.Hash()] = tx
+	f.nonce = tx.Nonce() + 1
+	return tx.Hash(), nil
+}
+
+func (f *fakeEth) GetTransactionByHash(hash common.Hash) *types.Transaction {
+	return f.sent[hash]
+}
+
+func newEndpoint(t *testing.T, eth *fakeEth) string {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", eth); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	t.Cleanup(server.Stop)
+	return httpServer.URL
+}
+
+func newFailingEndpoint(t *testing.T) string {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(httpServer.Close)
+	return httpServer.URL
+}
+
+// newRateLimitedEndpoint answers 429 Too Many Requests to the first requests, then serves the fake.
+func newRateLimitedEndpoint(t *testing.T, eth *fakeEth, rejected int) (string, *int) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", eth); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	requests := 0
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= rejected {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+	return httpServer.URL, &requests
+}
+
+// newTimingOutEndpoint serves the first requests but answers them with 504 Gateway Timeout, like a proxy giving
+// up on a node that still handled the request.
+func newTimingOutEndpoint(t *testing.T, eth *fakeEth, timedOut int) string {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", eth); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	requests := 0
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= timedOut {
+			server.ServeHTTP(httptest.NewRecorder(), r)
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+	return httpServer.URL
+}
+
+func TestReadFailsOverToHealthyEndpoint(t *testing.T) {
+	ctx := context.Background()
+	c, err := Dial(ctx, newFailingEndpoint(t), newEndpoint(t, &fakeEth{chainId: 43114, head: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 2; i++ {
+		chainId, err := c.ChainID(ctx)
+		if err != nil {
+			t.Fatalf("ChainID() error = %v", err)
+		}
+		if chainId.Int64() != 43114 {
+			t.Errorf("ChainID() = %v, want 43114", chainId)
+		}
+	}
+	if c.endpoints[0].healthy {
+		t.Error("failing endpoint is still healthy")
+	}
+}
+
+func TestCheckHealthMarksLaggingEndpoints(t *testing.T) {
+	ctx := context.Background()
+	c, err := Dial(ctx,
+		newEndpoint(t, &fakeEth{chainId: 1, head: 100}),
+		newEndpoint(t, &fakeEth{chainId: 1, head: 100 - DefaultMaxHeadLag - 1}),
+		newEndpoint(t, &fakeEth{chainId: 1, head: 100 - DefaultMaxHeadLag}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.CheckHealth(ctx)
+	want := []bool{true, false, true}
+	for i, e := range c.endpoints {
+		if e.healthy != want[i] {
+			t.Errorf("endpoint %d healthy = %v, want %v", i, e.healthy, want[i])
+		}
+	}
+
+	// Writes go to the primary first, unhealthy endpoints are tried last
+	order := c.writeOrder()
+	if order[0] != c.endpoints[0] || order[2] != c.endpoints[1] {
+		t.Errorf("writeOrder() does not start with the primary and end with the lagging endpoint")
+	}
+}
+
+func TestRateLimitedEndpointStaysHealthy(t *testing.T) {
+	ctx := context.Background()
+	url, requests := newRateLimitedEndpoint(t, &fakeEth{chainId: 43114, head: 10}, 2)
+	options := DefaultOptions
+	options.Retry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+	c, err := DialWithOptions(ctx, options, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	chainId, err := c.ChainID(ctx)
+	if err != nil {
+		t.Fatalf("ChainID() error = %v, want a success after backing off", err)
+	}
+	if chainId.Int64() != 43114 {
+		t.Errorf("ChainID() = %v, want 43114", chainId)
+	}
+	if *requests != 3 {
+		t.Errorf("%d requests, want 2 rate limited ones and a successful retry", *requests)
+	}
+	if !c.endpoints[0].healthy {
+		t.Error("rate limiting endpoint is unhealthy")
+	}
+}
+
+func TestSendTransactionAfterTimeout(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: 0, Gas: 21000, GasPrice: big.NewInt(1)}), types.NewEIP155Signer(big.NewInt(1)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		nonce   uint64
+		wantErr bool
+	}{
+		{name: "first attempt mined", nonce: 0},
+		{name: "nonce used by another transaction", nonce: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			options := DefaultOptions
+			options.Retry = RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+			c, err := DialWithOptions(ctx, options, newTimingOutEndpoint(t, &fakeEth{chainId: 1, nonce: tt.nonce}, 1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			err = c.SendTransaction(ctx, tx)
+			if tt.wantErr && err == nil {
+				t.Error("SendTransaction() error = nil, want nonce too low")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("SendTransaction() error = %v, want the retry of the mined transaction to succeed", err)
+			}
+		})
+	}
+}
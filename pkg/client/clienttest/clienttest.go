@@ -0,0 +1,38 @@
+// Package clienttest serves fake nodes to the tests of code using the rpc client. A fake node is any value
+// whose exported methods answer the eth namespace, such as GetBlockByNumber for eth_getBlockByNumber.
+package clienttest
+
+import (
+	"activity-bot/pkg/client"
+	"context"
+	"github.com/ethereum/go-ethereum/rpc"
+	"net/http/httptest"
+	"testing"
+)
+
+// NewServer returns an rpc server answering the eth namespace with the fake node, stopped when the test ends.
+func NewServer(t testing.TB, eth interface{}) *rpc.Server {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", eth); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	return server
+}
+
+// Serve serves the fake node over HTTP until the test ends and returns its url.
+func Serve(t testing.TB, eth interface{}) string {
+	httpServer := httptest.NewServer(NewServer(t, eth))
+	t.Cleanup(httpServer.Close)
+	return httpServer.URL
+}
+
+// Dial returns a client of the urls, closed when the test ends.
+func Dial(t testing.TB, urls ...string) *client.Client {
+	cl, err := client.Dial(context.Background(), urls...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cl.Close)
+	return cl
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting the requests sent to an endpoint. A nil limiter does not limit.
+type RateLimiter struct {
+	rate   float64 // Tokens added per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	lock   *sync.Mutex
+}
+
+// NewRateLimiter returns a limiter allowing requestsPerSecond on average and bursts of up to burst
+// requests, or nil if requestsPerSecond is not positive.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		lock:   &sync.Mutex{},
+	}
+}
+
+// Wait blocks until n requests may be sent or the context is done. Batches bigger than the burst
+// wait for a full bucket.
+func (r *RateLimiter) Wait(ctx context.Context, n int) error {
+	if r == nil {
+		return nil
+	}
+	for {
+		r.lock.Lock()
+		now := time.Now()
+		r.tokens += now.Sub(r.last).Seconds() * r.rate
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+		r.last = now
+
+		needed := float64(n)
+		if needed > r.burst {
+			needed = r.burst
+		}
+		if r.tokens >= needed {
+			r.tokens -= needed
+			r.lock.Unlock()
+			return nil
+		}
+		wait := time.Duration((needed - r.tokens) / r.rate * float64(time.Second))
+		r.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors answered by nodes for requests that will never succeed, whatever the number of attempts.
+var permanentErrorMessages = []string{
+	"execution reverted",
+	"nonce too low",
+	"insufficient funds",
+	"already known",
+	"replacement transaction underpriced",
+	"intrinsic gas too low",
+	"gas required exceeds allowance",
+}
+
+// Errors answered by providers when they are overloaded or rate limiting.
+var retryableErrorMessages = []string{
+	"rate limit",
+	"too many requests",
+	"limit exceeded",
+	"timeout",
+	"timed out",
+	"header not found",
+}
+
+// RetryPolicy retries retryable errors with a jittered exponential backoff.
+type RetryPolicy struct {
+	MaxAttempts    int // Attempts including the first one, the call is not retried below 2
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+}
+
+// Backoff returns how long to wait before the retry following the given attempt, starting at 0.
+// The delay doubles with each attempt up to MaxBackoff, the second half of it is random.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 0; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	half := backoff / 2
+	if half <= 0 {
+		return backoff
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// IsRetryable tells whether the request failing with err may succeed if sent again: timeouts,
+// rate limiting, server errors and transport errors are, reverts and rejected transactions are not.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	for _, permanent := range permanentErrorMessages {
+		if strings.Contains(message, permanent) {
+			return false
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		for _, retryable := range retryableErrorMessages {
+			if strings.Contains(message, retryable) {
+				return true
+			}
+		}
+		return false
+	}
+	// Transport errors such as a reset connection
+	return true
+}
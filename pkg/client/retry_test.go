@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+	"io"
+	"testing"
+	"time"
+)
+
+type jsonRpcError struct {
+	code    int
+	message string
+}
+
+func (e jsonRpcError) Error() string  { return e.message }
+func (e jsonRpcError) ErrorCode() int { return e.code }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "too many requests", err: rpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, want: true},
+		{name: "bad gateway", err: rpc.HTTPError{StatusCode: 502, Status: "502 Bad Gateway"}, want: true},
+		{name: "unauthorized", err: rpc.HTTPError{StatusCode: 401, Status: "401 Unauthorized"}, want: false},
+		{name: "provider rate limit", err: jsonRpcError{code: -32005, message: "daily request count exceeded, request rate limited"}, want: true},
+		{name: "deadline exceeded", err: fmt.Errorf("request: %w", context.DeadlineExceeded), want: true},
+		{name: "connection reset", err: io.ErrUnexpectedEOF, want: true},
+		{name: "revert", err: jsonRpcError{code: 3, message: "execution reverted: Stargate: slippage too high"}, want: false},
+		{name: "nonce too low", err: jsonRpcError{code: -32000, message: "nonce too low"}, want: false},
+		{name: "not found", err: ethereum.NotFound, want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "no error", err: nil, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffIsBoundedAndGrows(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt := 0; attempt < 8; attempt++ {
+		ceiling := policy.InitialBackoff << attempt
+		if ceiling > policy.MaxBackoff {
+			ceiling = policy.MaxBackoff
+		}
+		for i := 0; i < 20; i++ {
+			backoff := policy.Backoff(attempt)
+			if backoff < ceiling/2 || backoff > ceiling {
+				t.Fatalf("Backoff(%d) = %v, want within [%v, %v]", attempt, backoff, ceiling/2, ceiling)
+			}
+		}
+	}
+}
+
+func TestRateLimiterWaitsForTokens(t *testing.T) {
+	limiter := NewRateLimiter(20, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.Wait(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The burst covers two requests, the two others need 1/20s each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("4 requests took %v, want at least 100ms", elapsed)
+	}
+
+	slow := NewRateLimiter(0.001, 1)
+	if err := slow.Wait(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := slow.Wait(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() error = %v, want context.Canceled", err)
+	}
+}
@@ -1,6 +0,0 @@
-package constants
-
-const (
-	AVALANCHE = "https://avalanche-mainnet.infura.io/v3/"
-	POLYGON   = "https://polygon-mainnet.infura.io/v3/"
-)
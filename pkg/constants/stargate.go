@@ -1,6 +0,0 @@
-package constants
-
-const (
-	LZ_AVA_USDC = 1
-	LZ_FTM_USDC = 1
-)
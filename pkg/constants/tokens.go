@@ -1,20 +0,0 @@
-package constants
-
-const (
-	AVA_WAVAX                   = "0xB31f66AA3C1e785363F0875A1B74E27b85FD66c7"
-	AVA_NATIVE                  = "0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE"
-	AVA_USDC                    = "0xB97EF9Ef8734C71904D8002F8b6Bc66Dd9c48a6E"
-	AVA_BTCB                    = "0x152b9d0FdC40C096757F570A51E494bd4b943E50"
-	AVA_WOO_SWAP_CONTRACT       = "0xC22FBb3133dF781E6C25ea6acebe2D2Bb8CeA2f9"
-	AVA_USDC_CONTRACT           = "0xB97EF9Ef8734C71904D8002F8b6Bc66Dd9c48a6E"
-	AVA_USDCE_CONTRACT          = "0xA7D7079b0FEaD91F3e65f86E8915Cb59c1a4C664"
-	AVA_STARGATE_CONTRACT       = "0x45A01E4e04F14f7A4a6702c74187c5F6222033cd"
-	AVA_BITCOIN_BRIDGE_CONTRACT = "0x2297aEbD383787A160DD0d9F71508148769342E3"
-
-	FTM_STARGATE_CONTRACT = "0xAf5191B0De278C7286d6C7CC6ab6BB8A73bA2Cd6"
-	FTM_USDC_CONTRACT     = "0x04068DA6C83AFCFA0e13ba15A6696662335D5B75"
-
-	LZ_AVA_CHAIN_ID  = 106 // https://stargateprotocol.gitbook.io/stargate/developers/chain-ids
-	LZ_FTM_CHAIN_ID  = 112
-	LZ_POLY_CHAIN_ID = 109
-)
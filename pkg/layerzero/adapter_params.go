@@ -0,0 +1,125 @@
+package layerzero
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+)
+
+// Versions of the LayerZero v1 relayer adapter params.
+const (
+	// AdapterParamsV1 only sets the gas of the destination call.
+	AdapterParamsV1 uint16 = 1
+	// AdapterParamsV2 also airdrops native token to an address on the destination chain.
+	AdapterParamsV2 uint16 = 2
+)
+
+const (
+	adapterParamsV1Length = 2 + 32
+	adapterParamsV2Length = 2 + 32 + 32 + common.AddressLength
+)
+
+// AdapterParams are the LayerZero v1 relayer adapter params, encoded as
+// abi.encodePacked(uint16 version, uint256 dstGas[, uint256 nativeAmount, address nativeAddress]).
+type AdapterParams struct {
+	Version       uint16
+	DstGas        uint64         // Gas of the call on the destination chain
+	NativeAmount  *big.Int       // Native token airdropped on the destination chain, version 2 only
+	NativeAddress common.Address // Receiver of the airdrop, version 2 only
+}
+
+// NewAdapterParams returns version 1 params with the destination gas.
+func NewAdapterParams(dstGas uint64) AdapterParams {
+	return AdapterParams{
+		Version: AdapterParamsV1,
+		DstGas:  dstGas,
+	}
+}
+
+// NewAirdropAdapterParams returns version 2 params airdropping the native amount to the address.
+func NewAirdropAdapterParams(dstGas uint64, nativeAmount *big.Int, nativeAddress common.Address) AdapterParams {
+	return AdapterParams{
+		Version:       AdapterParamsV2,
+		DstGas:        dstGas,
+		NativeAmount:  nativeAmount,
+		NativeAddress: nativeAddress,
+	}
+}
+
+// Airdrop returns the native amount airdropped on the destination chain, zero for version 1 params.
+func (p AdapterParams) Airdrop() *big.Int {
+	if p.Version != AdapterParamsV2 || p.NativeAmount == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Set(p.NativeAmount)
+}
+
+func (p AdapterParams) Validate() error {
+	switch p.Version {
+	case AdapterParamsV1:
+		if p.NativeAmount != nil && p.NativeAmount.Sign() != 0 {
+			return fmt.Errorf("adapter params version 1 cannot airdrop %s", p.NativeAmount)
+		}
+		if p.NativeAddress != (common.Address{}) {
+			return fmt.Errorf("adapter params version 1 cannot airdrop to %s", p.NativeAddress.Hex())
+		}
+	case AdapterParamsV2:
+		if p.NativeAmount == nil || p.NativeAmount.Sign() < 0 {
+			return fmt.Errorf("adapter params version 2 need a native amount, got %v", p.NativeAmount)
+		}
+		if p.NativeAmount.BitLen() > 256 {
+			return fmt.Errorf("adapter params native amount %s does not fit in uint256", p.NativeAmount)
+		}
+		if p.NativeAddress == (common.Address{}) {
+			return fmt.Errorf("adapter params version 2 need a native address")
+		}
+	default:
+		return fmt.Errorf("unknown adapter params version %d", p.Version)
+	}
+	return nil
+}
+
+// Encode validates and packs the params as expected by the LayerZero endpoint.
+func (p AdapterParams) Encode() ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	encoded := make([]byte, 0, adapterParamsV2Length)
+	encoded = binary.BigEndian.AppendUint16(encoded, p.Version)
+	encoded = append(encoded, common.LeftPadBytes(new(big.Int).SetUint64(p.DstGas).Bytes(), 32)...)
+	if p.Version == AdapterParamsV2 {
+		encoded = append(encoded, common.LeftPadBytes(p.NativeAmount.Bytes(), 32)...)
+		encoded = append(encoded, p.NativeAddress.Bytes()...)
+	}
+	return encoded, nil
+}
+
+// DecodeAdapterParams unpacks and validates encoded params.
+func DecodeAdapterParams(encoded []byte) (AdapterParams, error) {
+	if len(encoded) < 2 {
+		return AdapterParams{}, fmt.Errorf("adapter params too short: %d bytes", len(encoded))
+	}
+	p := AdapterParams{Version: binary.BigEndian.Uint16(encoded)}
+	switch p.Version {
+	case AdapterParamsV1:
+		if len(encoded) != adapterParamsV1Length {
+			return AdapterParams{}, fmt.Errorf("adapter params version 1 must be %d bytes, got %d", adapterParamsV1Length, len(encoded))
+		}
+	case AdapterParamsV2:
+		if len(encoded) != adapterParamsV2Length {
+			return AdapterParams{}, fmt.Errorf("adapter params version 2 must be %d bytes, got %d", adapterParamsV2Length, len(encoded))
+		}
+		p.NativeAmount = new(big.Int).SetBytes(encoded[34:66])
+		p.NativeAddress = common.BytesToAddress(encoded[66:])
+	default:
+		return AdapterParams{}, fmt.Errorf("unknown adapter params version %d", p.Version)
+	}
+
+	dstGas := new(big.Int).SetBytes(encoded[2:34])
+	if !dstGas.IsUint64() {
+		return AdapterParams{}, fmt.Errorf("adapter params destination gas %s does not fit in uint64", dstGas)
+	}
+	p.DstGas = dstGas.Uint64()
+	return p, p.Validate()
+}
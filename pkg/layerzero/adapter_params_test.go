@@ -0,0 +1,133 @@
+package layerzero
+
+import (
+	"bytes"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"testing"
+)
+
+var account = common.HexToAddress("0x3654114f003C108A339664f909131b4C07b0F779")
+
+func equalParams(a, b AdapterParams) bool {
+	return a.Version == b.Version &&
+		a.DstGas == b.DstGas &&
+		a.Airdrop().Cmp(b.Airdrop()) == 0 &&
+		a.NativeAddress == b.NativeAddress
+}
+
+func TestAdapterParamsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     AdapterParams
+		wantLength int
+	}{
+		{
+			name:       "version 1",
+			params:     NewAdapterParams(200000),
+			wantLength: 34,
+		},
+		{
+			name:       "version 2",
+			params:     NewAirdropAdapterParams(250000, big.NewInt(1e16), account),
+			wantLength: 86,
+		},
+		{
+			name:       "version 2 without airdrop",
+			params:     NewAirdropAdapterParams(250000, big.NewInt(0), account),
+			wantLength: 86,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.params.Encode()
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if len(encoded) != tt.wantLength {
+				t.Errorf("Encode() length = %d, want %d", len(encoded), tt.wantLength)
+			}
+			decoded, err := DecodeAdapterParams(encoded)
+			if err != nil {
+				t.Fatalf("DecodeAdapterParams() error = %v", err)
+			}
+			if !equalParams(decoded, tt.params) {
+				t.Errorf("DecodeAdapterParams() = %+v, want %+v", decoded, tt.params)
+			}
+		})
+	}
+}
+
+func TestDecodeAdapterParamsMatchesRelayerEncoding(t *testing.T) {
+	// Params used by the BTC.b bridge before the builder: version 2, 250000 gas, no airdrop
+	encoded := common.FromHex("0002000000000000000000000000000000000000000000000000000000000003d0900000000000000000000000000000000000000000000000000000000000000000" + account.Hex()[2:])
+	decoded, err := DecodeAdapterParams(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewAirdropAdapterParams(250000, big.NewInt(0), account)
+	if !equalParams(decoded, want) {
+		t.Errorf("DecodeAdapterParams() = %+v, want %+v", decoded, want)
+	}
+	reencoded, err := want.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reencoded, encoded) {
+		t.Errorf("Encode() = %x, want %x", reencoded, encoded)
+	}
+}
+
+func TestAdapterParamsValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		params AdapterParams
+	}{
+		{
+			name:   "unknown version",
+			params: AdapterParams{Version: 3, DstGas: 200000},
+		},
+		{
+			name:   "version 1 with airdrop",
+			params: AdapterParams{Version: AdapterParamsV1, DstGas: 200000, NativeAmount: big.NewInt(1)},
+		},
+		{
+			name:   "version 2 without address",
+			params: NewAirdropAdapterParams(200000, big.NewInt(1), common.Address{}),
+		},
+		{
+			name:   "version 2 without amount",
+			params: NewAirdropAdapterParams(200000, nil, account),
+		},
+		{
+			name:   "version 2 with negative amount",
+			params: NewAirdropAdapterParams(200000, big.NewInt(-1), account),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.params.Encode(); err == nil {
+				t.Error("Encode() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestDecodeAdapterParamsRejectsMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty", ""},
+		{"truncated version 1", "0001000000000000000000000000000000000000000000000000000000000003d0"},
+		{"unknown version", "0003000000000000000000000000000000000000000000000000000000000003d090"},
+		{"gas overflow", "00010000000000000000000000000000000000000000000100000000000000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeAdapterParams(common.FromHex(tt.encoded)); err == nil {
+				t.Error("DecodeAdapterParams() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -20,6 +20,25 @@ func NewSupplier(unit, min, max int64) *Supplier {
 	}
 }
 
+// NewFixedSupplier returns a supplier always supplying the value, for instance an amount received earlier.
+func NewFixedSupplier(value *big.Int) *Supplier {
+	return &Supplier{
+		unit: big.NewInt(1),
+		min:  new(big.Int).Set(value),
+		max:  new(big.Int).Set(value),
+	}
+}
+
+// NewRangeSupplier returns a supplier of values between min and max in the minimal unit, for bounds which do not
+// fit a unit multiple.
+func NewRangeSupplier(min, max *big.Int) *Supplier {
+	return &Supplier{
+		unit: big.NewInt(1),
+		min:  new(big.Int).Set(min),
+		max:  new(big.Int).Set(max),
+	}
+}
+
 func (s *Supplier) Min() *big.Int {
 	return s.min
 }
@@ -30,9 +49,21 @@ func (s *Supplier) Max() *big.Int {
 
 func (s *Supplier) Supply() *big.Int {
 	diff := new(big.Int).Sub(s.max, s.min)
+	if diff.Sign() <= 0 {
+		return new(big.Int).Set(s.min)
+	}
 	r, err := rand.Int(rand.Reader, diff)
 	if err != nil {
 		panic(err)
 	}
 	return new(big.Int).Add(r, s.min)
 }
+
+// Pick returns one of the items chosen uniformly at random.
+func Pick[T any](items []T) T {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(len(items))))
+	if err != nil {
+		panic(err)
+	}
+	return items[i.Int64()]
+}
@@ -2,6 +2,7 @@ package random
 
 import (
 	"github.com/ethereum/go-ethereum/params"
+	"math/big"
 	"testing"
 )
 
@@ -14,3 +15,13 @@ func TestSupply(t *testing.T) {
 		}
 	}
 }
+
+func TestFixedSupply(t *testing.T) {
+	value := big.NewInt(12345)
+	s := NewFixedSupplier(value)
+	for i := 0; i < 3; i++ {
+		if amount := s.Supply(); amount.Cmp(value) != 0 {
+			t.Errorf("Supply() = %v, want %v", amount, value)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package token
+
+import (
+	"activity-bot/pkg/abi/erc20"
+	"activity-bot/pkg/chain"
+	"activity-bot/pkg/random"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"strings"
+)
+
+// NativeDecimals are the decimals of the native token of EVM chains.
+const NativeDecimals = 18
+
+// Token is a token of a chain with its metadata, amounts are converted exactly between their decimal
+// representation, such as "12.345678", and the token minimal unit.
+type Token struct {
+	Chain    string
+	Address  common.Address // chain.NativeToken for the native token
+	Symbol   string
+	Decimals uint8
+}
+
+// Native returns the native token of the chain.
+func Native(c *chain.Chain) *Token {
+	return &Token{
+		Chain:    c.Name,
+		Address:  chain.NativeToken,
+		Symbol:   c.NativeSymbol,
+		Decimals: NativeDecimals,
+	}
+}
+
+// Resolve returns the token with the symbol of the chain's token book, its decimals are read on-chain. The
+// native symbol resolves to the native token.
+func Resolve(opts *bind.CallOpts, c *chain.Chain, backend bind.ContractCaller, symbol string) (*Token, error) {
+	if symbol == c.NativeSymbol {
+		return Native(c), nil
+	}
+	address, err := c.Token(symbol)
+	if err != nil {
+		return nil, err
+	}
+	caller, err := erc20.NewErc20Caller(address, backend)
+	if err != nil {
+		return nil, err
+	}
+	decimals, err := caller.Decimals(opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get decimals of %s on %s: %w", symbol, c.Name, err)
+	}
+	return &Token{
+		Chain:    c.Name,
+		Address:  address,
+		Symbol:   symbol,
+		Decimals: decimals,
+	}, nil
+}
+
+func (t *Token) IsNative() bool {
+	return t.Address == chain.NativeToken
+}
+
+// Parse converts a decimal amount of the token, such as "12.345678", to the minimal unit.
+func (t *Token) Parse(amount string) (*big.Int, error) {
+	units, err := ParseUnits(amount, t.Decimals)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s amount: %w", t.Symbol, err)
+	}
+	return units, nil
+}
+
+// Format converts an amount in the minimal unit to its decimal representation.
+func (t *Token) Format(units *big.Int) string {
+	return FormatUnits(units, t.Decimals)
+}
+
+// Supplier returns a supplier of amounts between the decimal amounts min and max, in the minimal unit.
+func (t *Token) Supplier(min, max string) (*random.Supplier, error) {
+	minUnits, err := t.Parse(min)
+	if err != nil {
+		return nil, err
+	}
+	maxUnits, err := t.Parse(max)
+	if err != nil {
+		return nil, err
+	}
+	if minUnits.Cmp(maxUnits) > 0 {
+		return nil, fmt.Errorf("minimum %s %s is above maximum %s %s", min, t.Symbol, max, t.Symbol)
+	}
+	return random.NewRangeSupplier(minUnits, maxUnits), nil
+}
+
+// ParseUnits converts a non-negative decimal amount to the minimal unit of a token with the decimals. It
+// rejects amounts with more fraction digits than decimals instead of rounding them.
+func ParseUnits(amount string, decimals uint8) (*big.Int, error) {
+	whole, fraction, hasFraction := strings.Cut(amount, ".")
+	if (whole == "" && fraction == "") || (hasFraction && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return nil, fmt.Errorf("%q is not a decimal amount", amount)
+	}
+	if len(fraction) > int(decimals) {
+		return nil, fmt.Errorf("%q has more than %d decimals", amount, decimals)
+	}
+
+	digits := whole + fraction + strings.Repeat("0", int(decimals)-len(fraction))
+	units, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a decimal amount", amount)
+	}
+	return units, nil
+}
+
+// FormatUnits converts an amount in the minimal unit of a token with the decimals to a decimal amount without
+// trailing zeros.
+func FormatUnits(units *big.Int, decimals uint8) string {
+	digits := new(big.Int).Abs(units).String()
+	sign := ""
+	if units.Sign() < 0 {
+		sign = "-"
+	}
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+	point := len(digits) - int(decimals)
+	whole, fraction := digits[:point], strings.TrimRight(digits[point:], "0")
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+// ConvertUnits converts an amount in the minimal unit of a token with the decimals to the minimal unit of a
+// token with other decimals, rounding down, such as the same USDC amount on chains using 6 and 18 decimals.
+func ConvertUnits(units *big.Int, decimals uint8, toDecimals uint8) *big.Int {
+	if toDecimals >= decimals {
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(toDecimals-decimals)), nil)
+		return new(big.Int).Mul(units, scale)
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals-toDecimals)), nil)
+	return new(big.Int).Quo(units, scale)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
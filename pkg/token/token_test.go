@@ -0,0 +1,107 @@
+package token
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		decimals uint8
+		want     string
+		wantErr  bool
+	}{
+		{"usdc", "12.345678", 6, "12345678", false},
+		{"whole", "1", 18, "1000000000000000000", false},
+		{"leading point", ".5", 6, "500000", false},
+		{"no decimals", "42", 0, "42", false},
+		{"large", "123456789012345678901234567890.1", 18, "123456789012345678901234567890100000000000000000", false},
+		{"excess precision", "0.1234567", 6, "", true},
+		{"negative", "-1", 6, "", true},
+		{"exponent", "1e6", 6, "", true},
+		{"trailing point", "1.", 6, "", true},
+		{"empty", "", 6, "", true},
+		{"point", ".", 6, "", true},
+		{"two points", "1.2.3", 6, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUnits(tt.amount, tt.decimals)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUnits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("ParseUnits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		units    string
+		decimals uint8
+		want     string
+	}{
+		{"12345678", 6, "12.345678"},
+		{"1000000", 6, "1"},
+		{"500000", 6, "0.5"},
+		{"1", 18, "0.000000000000000001"},
+		{"0", 6, "0"},
+		{"-1500000", 6, "-1.5"},
+		{"42", 0, "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			units, _ := new(big.Int).SetString(tt.units, 10)
+			if got := FormatUnits(units, tt.decimals); got != tt.want {
+				t.Errorf("FormatUnits() = %v, want %v", got, tt.want)
+			}
+			if units.Sign() >= 0 {
+				back, err := ParseUnits(tt.want, tt.decimals)
+				if err != nil || back.Cmp(units) != 0 {
+					t.Errorf("ParseUnits(FormatUnits()) = %v, %v, want %v", back, err, units)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertUnits(t *testing.T) {
+	tests := []struct {
+		name       string
+		units      string
+		decimals   uint8
+		toDecimals uint8
+		want       string
+	}{
+		{"more decimals", "1500000", 6, 18, "1500000000000000000"},
+		{"fewer decimals", "1500000000000000000", 18, 6, "1500000"},
+		{"rounds down", "1999999999999", 18, 6, "1"},
+		{"same decimals", "42", 6, 6, "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			units, _ := new(big.Int).SetString(tt.units, 10)
+			if got := ConvertUnits(units, tt.decimals, tt.toDecimals); got.String() != tt.want {
+				t.Errorf("ConvertUnits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSupplier(t *testing.T) {
+	usdc := &Token{Symbol: "USDC", Decimals: 6}
+	s, err := usdc.Supplier("0.5", "1.25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Min().Cmp(big.NewInt(500000)) != 0 || s.Max().Cmp(big.NewInt(1250000)) != 0 {
+		t.Errorf("Supplier() = [%v, %v], want [500000, 1250000]", s.Min(), s.Max())
+	}
+	if _, err := usdc.Supplier("2", "1"); err == nil {
+		t.Error("Supplier() error = nil, want an error for a minimum above the maximum")
+	}
+}
@@ -2,12 +2,21 @@ package util
 
 import (
 	"context"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/core/types"
 	"time"
 )
 
-func WaitForReceipt(tx *types.Transaction, awaiter *Waiter) (*types.Receipt, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// DefaultReceiptTimeout is used when no receipt timeout is given.
+const DefaultReceiptTimeout = 30 * time.Second
+
+// WaitForReceipt waits for the receipt of the transaction until the timeout elapses or the context is done.
+// A zero timeout uses DefaultReceiptTimeout.
+func WaitForReceipt(ctx context.Context, tx *types.Transaction, awaiter *Waiter, timeout time.Duration) (*types.Receipt, error) {
+	if timeout == 0 {
+		timeout = DefaultReceiptTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	return WaitForReceiptOrTimeout(tx, awaiter, ctx)
 }
@@ -21,6 +30,21 @@ func WaitForReceiptOrTimeout(tx *types.Transaction, awaiter *Waiter, ctx context
 	case receipt := <-receiptCh:
 		return receipt, nil
 	case <-ctx.Done():
+		awaiter.forgetTransaction(receiptCh)
+		return nil, ctx.Err()
+	}
+}
+
+func WaitForLogOrTimeout(filter ethereum.FilterQuery, predicate func(types.Log) bool, awaiter *Waiter, ctx context.Context) (*types.Log, error) {
+	logCh, err := awaiter.WaitForLog(filter, predicate)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case l := <-logCh:
+		return &l, nil
+	case <-ctx.Done():
+		awaiter.forgetLog(logCh)
 		return nil, ctx.Err()
 	}
 }
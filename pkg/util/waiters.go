@@ -1,18 +1,35 @@
 package util
 
 import (
+	"activity-bot/pkg/client"
 	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"log"
 	"sync"
 	"time"
 )
 
+const (
+	minPollInterval = 250 * time.Millisecond
+	maxPollInterval = 15 * time.Second
+	// blockTimeSmoothing is the weight given to the previous estimate when a new block time is observed
+	blockTimeSmoothing = 4
+	pollRequestTimeout = 10 * time.Second
+	// maxLogBlockRange is the largest range requested in a single eth_getLogs call, most providers reject bigger ones
+	maxLogBlockRange = 2048
+)
+
+// resubscribeDelay is how long to wait before subscribing again after a subscription failed.
+var resubscribeDelay = 5 * time.Second
+
 type transactionWaiter struct {
 	TxHash   common.Hash
-	Listener chan<- *types.Receipt
+	Listener chan *types.Receipt
 }
 
 type blockWaiter struct {
@@ -20,27 +37,41 @@ type blockWaiter struct {
 	Listener    chan<- interface{}
 }
 
+type logWaiter struct {
+	Filter    ethereum.FilterQuery
+	Predicate func(types.Log) bool
+	NextBlock uint64 // First block not scanned yet
+	Listener  chan types.Log
+}
+
 type Waiter struct {
-	client               *ethclient.Client
+	client               *client.Client
 	transactionWaiters   []transactionWaiter
 	blockWaiters         []blockWaiter
+	logWaiters           []logWaiter
 	lastBlockId          uint64
 	lastBlockIdUpdatedAt time.Time
+	lastHeader           *types.Header
+	blockTime            time.Duration // Observed average block time, only valid once blockTimeKnown is set
+	blockTimeKnown       bool
 	supportsSubscribing  bool
-	pollTimeDuration     time.Duration
+	pollTimeDuration     time.Duration   // Used as poll interval until the block time is known
+	ctx                  context.Context // Bounds every RPC call of the waiter, replaced on start
 	lock                 *sync.Mutex
 }
 
-func NewWaiter(client *ethclient.Client, pollTimeDuration time.Duration, supportsSubscribing bool) *Waiter {
+func NewWaiter(client *client.Client, pollTimeDuration time.Duration, supportsSubscribing bool) *Waiter {
 	return &Waiter{
 		client:               client,
 		transactionWaiters:   make([]transactionWaiter, 0),
 		blockWaiters:         make([]blockWaiter, 0),
+		logWaiters:           make([]logWaiter, 0),
 		lastBlockId:          0,
 		lastBlockIdUpdatedAt: time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
 		lock:                 &sync.Mutex{},
 		supportsSubscribing:  supportsSubscribing,
 		pollTimeDuration:     pollTimeDuration,
+		ctx:                  context.Background(),
 	}
 }
 
@@ -48,7 +79,8 @@ func (w *Waiter) WaitForTransaction(txHash common.Hash) (<-chan *types.Receipt,
 	// Make sure to lock the waiter before accessing the transactionWaiters
 	w.lock.Lock()
 	defer w.lock.Unlock()
-	listener := make(chan *types.Receipt)
+	// Buffered so that notifying never blocks the waiter, even if the caller stopped waiting
+	listener := make(chan *types.Receipt, 1)
 	w.transactionWaiters = append(w.transactionWaiters, transactionWaiter{
 		TxHash:   txHash,
 		Listener: listener,
@@ -61,8 +93,8 @@ func (w *Waiter) WaitForBlocks(blocksToWait uint64) (<-chan interface{}, error)
 	// Make sure to lock the waiter before accessing the transactionWaiters
 	w.lock.Lock()
 	defer w.lock.Unlock()
-	listener := make(chan interface{})
-	err := w.updateBlockId()
+	listener := make(chan interface{}, 1)
+	err := w.updateBlockId(w.ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -75,15 +107,88 @@ func (w *Waiter) WaitForBlocks(blocksToWait uint64) (<-chan interface{}, error)
 	return listener, nil
 }
 
-func (w *Waiter) Start() context.CancelFunc {
-	ctx, cancel := context.WithCancel(context.Background())
+// BlockNumber returns the latest block number known by the waiter, refreshing it if it is outdated.
+func (w *Waiter) BlockNumber(ctx context.Context) (uint64, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	err := w.updateBlockId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return w.lastBlockId, nil
+}
+
+// WaitForLog notifies the returned channel with the first log matching the filter for which the predicate
+// returns true. A nil predicate accepts any log. Scanning starts at filter.FromBlock, or at the current head
+// if it is nil, and resumes where it stopped after a failed request or a lost subscription, so logs emitted
+// in the meantime are not missed. filter.ToBlock is ignored, the waiter keeps scanning new blocks.
+func (w *Waiter) WaitForLog(filter ethereum.FilterQuery, predicate func(types.Log) bool) (<-chan types.Log, error) {
+	if filter.BlockHash != nil {
+		return nil, errors.New("waiting for logs of a single block hash is not supported")
+	}
+	if predicate == nil {
+		predicate = func(types.Log) bool { return true }
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	var nextBlock uint64
+	if filter.FromBlock != nil {
+		nextBlock = filter.FromBlock.Uint64()
+	} else {
+		err := w.updateBlockId(w.ctx)
+		if err != nil {
+			return nil, err
+		}
+		nextBlock = w.lastBlockId
+	}
+
+	listener := make(chan types.Log, 1)
+	w.logWaiters = append(w.logWaiters, logWaiter{
+		Filter:    filter,
+		Predicate: predicate,
+		NextBlock: nextBlock,
+		Listener:  listener,
+	})
+
+	return listener, nil
+}
+
+// forgetTransaction stops waiting for the receipt delivered to the listener.
+func (w *Waiter) forgetTransaction(listener <-chan *types.Receipt) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	updatedWaiters := make([]transactionWaiter, 0)
+	for _, waiter := range w.transactionWaiters {
+		if waiter.Listener != listener {
+			updatedWaiters = append(updatedWaiters, waiter)
+		}
+	}
+	w.transactionWaiters = updatedWaiters
+}
+
+// forgetLog stops waiting for the log delivered to the listener.
+func (w *Waiter) forgetLog(listener <-chan types.Log) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	updatedWaiters := make([]logWaiter, 0)
+	for _, waiter := range w.logWaiters {
+		if waiter.Listener != listener {
+			updatedWaiters = append(updatedWaiters, waiter)
+		}
+	}
+	w.logWaiters = updatedWaiters
+}
+
+// Start listens for new blocks until the returned function is called or the parent context is done,
+// cancelling either also aborts the in-flight RPC calls of the waiter.
+func (w *Waiter) Start(parent context.Context) context.CancelFunc {
+	ctx, cancel := context.WithCancel(parent)
+	w.lock.Lock()
+	w.ctx = ctx
+	w.lock.Unlock()
 	if w.supportsSubscribing {
-		go func() {
-			err := w.eventStartListening(ctx.Done())
-			if err != nil {
-				log.Fatal(err)
-			}
-		}()
+		go w.eventStartListening(ctx.Done())
 	} else {
 		go w.pollingStartListening(ctx.Done())
 	}
@@ -95,140 +200,270 @@ func (w *Waiter) pollingStartListening(done <-chan struct{}) {
 		select {
 		case <-done:
 			log.Println("Stop listening for new blocks")
-			break
-		default:
-			time.Sleep(w.pollTimeDuration)
-			w.lock.Lock()
-			// If there are no more waiters, stop listening
-			if len(w.transactionWaiters) == 0 && len(w.blockWaiters) == 0 {
-				log.Println("No more waiters, unsubscribing from new block headers")
-				w.lock.Unlock()
-				continue
-			}
-
-			if w.hasTxWaiters() {
-				w.tryNotifyTxReceipt()
-			}
-
-			if w.hasBlockWaiters() {
-				err := w.updateBlockId()
-				if err != nil {
-					log.Printf("Failed to update block id: %v", err)
-					w.lock.Unlock()
-					continue
-				}
-				w.notifyBlockWaiters()
-			}
-			w.lock.Unlock()
+			return
+		case <-time.After(w.PollInterval()):
 		}
+
+		w.poll()
 	}
 }
 
-func (w *Waiter) eventStartListening(done <-chan struct{}) error {
+func (w *Waiter) poll() {
 	w.lock.Lock()
-	headers := make(chan *types.Header)
-	sub, err := w.client.SubscribeNewHead(context.Background(), headers)
-	if err != nil {
-		log.Fatalf("Failed to subscribe to new block headers: %v\n", err)
-		return err
+	defer w.lock.Unlock()
+	// If there are no waiters, there is nothing to poll for
+	if !w.hasTxWaiters() && !w.hasBlockWaiters() && !w.hasLogWaiters() {
+		return
 	}
-	w.lock.Unlock()
 
-	select {
+	err := w.pollBatch()
+	if err != nil {
+		log.Printf("Failed to poll receipts, logs and latest block: %v", err)
+	}
+}
 
-	case <-done:
-		log.Println("Stop listening for new blocks")
-		sub.Unsubscribe()
-		break
-	default:
-		w.lock.Lock()
+// pollBatch fetches the latest header, the receipts of all pending transactions and the logs of the blocks
+// not yet scanned by the log waiters in a single JSON-RPC batch request, then notifies every waiter that can
+// be resolved. Log ranges end at the head known from the previous batch, which may come from another endpoint,
+// so a range only counts as scanned up to the head returned first in the same batch.
+func (w *Waiter) pollBatch() error {
+	receipts := make([]*types.Receipt, len(w.transactionWaiters))
+	logs := make([][]types.Log, len(w.logWaiters))
+	logElems := make([]int, len(w.logWaiters))
+	logRangeEnds := make([]uint64, len(w.logWaiters))
+	var header *types.Header
 
-		if len(w.transactionWaiters) == 0 && len(w.blockWaiters) == 0 {
-			log.Println("No more waiters, unsubscribing from new block headers")
-			sub.Unsubscribe()
-			w.lock.Unlock()
-			return nil
+	// The header comes first, a node answers the elements in order so its logs cover at least that head
+	batch := make([]rpc.BatchElem, 0, len(w.transactionWaiters)+len(w.logWaiters)+1)
+	batch = append(batch, rpc.BatchElem{
+		Method: "eth_getBlockByNumber",
+		Args:   []interface{}{"latest", false},
+		Result: &header,
+	})
+	receiptOffset := len(batch)
+	for i, waiter := range w.transactionWaiters {
+		batch = append(batch, rpc.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Args:   []interface{}{waiter.TxHash},
+			Result: &receipts[i],
+		})
+	}
+	for i, waiter := range w.logWaiters {
+		logElems[i] = -1
+		if w.lastBlockId == 0 || waiter.NextBlock > w.lastBlockId {
+			continue
 		}
+		logRangeEnds[i] = w.lastBlockId
+		if logRangeEnds[i]-waiter.NextBlock >= maxLogBlockRange {
+			logRangeEnds[i] = waiter.NextBlock + maxLogBlockRange - 1
+		}
+		logElems[i] = len(batch)
+		batch = append(batch, rpc.BatchElem{
+			Method: "eth_getLogs",
+			Args:   []interface{}{toFilterArg(waiter.Filter, waiter.NextBlock, logRangeEnds[i])},
+			Result: &logs[i],
+		})
+	}
 
-		select {
-		case err := <-sub.Err():
-			w.lock.Unlock()
-			log.Fatalf("Subscription error: %v", err)
-		case header := <-headers:
-			w.lastBlockId = header.Number.Uint64()
+	ctx, cancel := context.WithTimeout(w.ctx, pollRequestTimeout)
+	defer cancel()
+	if err := w.client.BatchCallContext(ctx, batch); err != nil {
+		return err
+	}
 
-			if len(w.blockWaiters) > 0 {
-				w.notifyBlockWaiters()
-			}
+	var batchHead uint64
+	headKnown := false
+	if batch[0].Error != nil {
+		log.Printf("Failed to get latest block: %v", batch[0].Error)
+	} else if header != nil {
+		batchHead = header.Number.Uint64()
+		headKnown = true
+		w.observeHeader(header)
+		w.notifyBlockWaiters()
+	}
 
-			if len(w.transactionWaiters) > 0 {
-				block, err := w.client.BlockByHash(context.Background(), header.Hash())
-				log.Printf("Failed to get block details for block %v: %v", header.Number, err)
-				w.notifyTxWaiters(block)
-			}
+	updatedWaiters := make([]transactionWaiter, 0)
+	for i, waiter := range w.transactionWaiters {
+		if err := batch[receiptOffset+i].Error; err != nil {
+			log.Printf("Failed to get receipt for transaction %v: %v", waiter.TxHash, err)
+			updatedWaiters = append(updatedWaiters, waiter)
+			continue
+		}
+		if receipts[i] == nil {
+			updatedWaiters = append(updatedWaiters, waiter)
+			continue
 		}
+		waiter.Listener <- receipts[i]
+	}
+	w.transactionWaiters = updatedWaiters
 
-		w.lock.Unlock()
+	updatedLogWaiters := make([]logWaiter, 0)
+	for i, waiter := range w.logWaiters {
+		if logElems[i] < 0 {
+			updatedLogWaiters = append(updatedLogWaiters, waiter)
+			continue
+		}
+		if err := batch[logElems[i]].Error; err != nil {
+			log.Printf("Failed to get logs from block %d: %v", waiter.NextBlock, err)
+			updatedLogWaiters = append(updatedLogWaiters, waiter)
+			continue
+		}
+		if match, ok := firstMatchingLog(logs[i], waiter.Predicate); ok {
+			waiter.Listener <- match
+			continue
+		}
+		// Without the head of the answering endpoint, nothing tells how far it scanned
+		if headKnown && batchHead >= waiter.NextBlock {
+			scannedTo := logRangeEnds[i]
+			if batchHead < scannedTo {
+				scannedTo = batchHead
+			}
+			waiter.NextBlock = scannedTo + 1
+		}
+		updatedLogWaiters = append(updatedLogWaiters, waiter)
 	}
+	w.logWaiters = updatedLogWaiters
 	return nil
 }
 
-func (w *Waiter) notifyBlockWaiters() {
-	updatedWaiters := make([]blockWaiter, 0)
-	for _, waiter := range w.blockWaiters {
-		if waiter.BlockToWait <= w.lastBlockId {
-			waiter.Listener <- nil
-		} else {
-			updatedWaiters = append(updatedWaiters, waiter)
+func firstMatchingLog(logs []types.Log, predicate func(types.Log) bool) (types.Log, bool) {
+	for _, l := range logs {
+		if !l.Removed && predicate(l) {
+			return l, true
 		}
 	}
-	w.blockWaiters = updatedWaiters
+	return types.Log{}, false
 }
 
-func (w *Waiter) tryNotifyTxReceipt() {
-	updatedWaiters := make([]transactionWaiter, 0)
-	for _, waiter := range w.transactionWaiters {
-		receipt, err := w.client.TransactionReceipt(context.Background(), waiter.TxHash)
-		if err != nil {
-			log.Printf("Failed to get receipt for transaction %v: %v", waiter.TxHash, err)
-			continue
+// toFilterArg builds the eth_getLogs parameter for the filter restricted to the given block range.
+func toFilterArg(filter ethereum.FilterQuery, fromBlock uint64, toBlock uint64) interface{} {
+	return map[string]interface{}{
+		"address":   filter.Addresses,
+		"topics":    filter.Topics,
+		"fromBlock": hexutil.EncodeUint64(fromBlock),
+		"toBlock":   hexutil.EncodeUint64(toBlock),
+	}
+}
+
+// PollInterval returns the interval used in polling mode. It follows the observed block time
+// of the chain, falling back to the configured poll duration until enough blocks were seen.
+func (w *Waiter) PollInterval() time.Duration {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	interval := w.pollTimeDuration
+	if w.blockTimeKnown {
+		interval = w.blockTime
+	}
+	if interval < minPollInterval {
+		return minPollInterval
+	}
+	if interval > maxPollInterval {
+		return maxPollInterval
+	}
+	return interval
+}
+
+// observeHeader records the latest head and updates the block time estimate.
+func (w *Waiter) observeHeader(header *types.Header) {
+	number := header.Number.Uint64()
+	if w.lastHeader != nil {
+		lastNumber := w.lastHeader.Number.Uint64()
+		if number <= lastNumber {
+			return
 		}
+		if header.Time >= w.lastHeader.Time {
+			elapsed := time.Duration(header.Time-w.lastHeader.Time) * time.Second
+			observed := elapsed / time.Duration(number-lastNumber)
+			if !w.blockTimeKnown {
+				w.blockTime = observed
+				w.blockTimeKnown = true
+			} else {
+				w.blockTime = (w.blockTime*(blockTimeSmoothing-1) + observed) / blockTimeSmoothing
+			}
+		}
+	}
+	w.lastHeader = header
+	w.lastBlockId = number
+	w.lastBlockIdUpdatedAt = time.Now()
+}
 
-		if receipt == nil {
-			continue
+// eventStartListening polls the waiters on every new head received through the subscription. When the
+// subscription fails it subscribes again after a delay, waiters resume from where they stopped.
+func (w *Waiter) eventStartListening(done <-chan struct{}) {
+	for {
+		err := w.listenNewHeads(done)
+		if err == nil {
+			log.Println("Stop listening for new blocks")
+			return
 		}
+		log.Printf("Subscription error, subscribing again in %v: %v", resubscribeDelay, err)
+		select {
+		case <-done:
+			log.Println("Stop listening for new blocks")
+			return
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
 
-		w.transactionWaiters = updatedWaiters
-		waiter.Listener <- receipt
+func (w *Waiter) listenNewHeads(done <-chan struct{}) error {
+	headers := make(chan *types.Header)
+	w.lock.Lock()
+	ctx := w.ctx
+	w.lock.Unlock()
+	sub, err := w.client.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	// Catch up with what happened while not subscribed, up to the current head since no header announced the
+	// blocks missed in the meantime
+	w.lock.Lock()
+	w.lastBlockIdUpdatedAt = time.Time{}
+	err = w.updateBlockId(ctx)
+	w.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	w.poll()
+	for {
+		select {
+		case <-done:
+			return nil
+		case err := <-sub.Err():
+			return err
+		case header := <-headers:
+			w.lock.Lock()
+			w.observeHeader(header)
+			w.lock.Unlock()
+			w.poll()
+		}
 	}
 }
 
-func (w *Waiter) notifyTxWaiters(block *types.Block) {
-	updatedWaiters := make([]transactionWaiter, 0)
-	for _, waiter := range w.transactionWaiters {
-		tx := block.Transaction(waiter.TxHash)
-		if tx == nil {
+func (w *Waiter) notifyBlockWaiters() {
+	updatedWaiters := make([]blockWaiter, 0)
+	for _, waiter := range w.blockWaiters {
+		if waiter.BlockToWait <= w.lastBlockId {
+			waiter.Listener <- nil
+		} else {
 			updatedWaiters = append(updatedWaiters, waiter)
-			continue
-		}
-		receipt, err := w.client.TransactionReceipt(context.Background(), waiter.TxHash)
-		if err != nil {
-			log.Printf("Failed to get receipt for transaction %v: %v", waiter.TxHash, err)
-			continue
 		}
-		w.transactionWaiters = updatedWaiters
-		waiter.Listener <- receipt
 	}
+	w.blockWaiters = updatedWaiters
 }
 
-func (w *Waiter) updateBlockId() error {
+func (w *Waiter) updateBlockId(ctx context.Context) error {
 	if w.lastBlockIdUpdatedAt.Before(time.Now().Add(-1 * time.Second)) {
-		block, err := w.client.BlockByNumber(context.Background(), nil)
+		ctx, cancel := context.WithTimeout(ctx, pollRequestTimeout)
+		defer cancel()
+		header, err := w.client.HeaderByNumber(ctx, nil)
 		if err != nil {
 			log.Printf("Failed to get latest block: %v\n", err)
 			return err
 		}
-		w.lastBlockId = block.NumberU64()
+		w.observeHeader(header)
 		w.lastBlockIdUpdatedAt = time.Now()
 		return nil
 	}
@@ -242,3 +477,7 @@ func (w *Waiter) hasTxWaiters() bool {
 func (w *Waiter) hasBlockWaiters() bool {
 	return len(w.blockWaiters) > 0
 }
+
+func (w *Waiter) hasLogWaiters() bool {
+	return len(w.logWaiters) > 0
+}
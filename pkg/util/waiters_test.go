@@ -0,0 +1,438 @@
+package util
+
+import (
+	"activity-bot/pkg/client/clienttest"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func header(number int64, timestamp uint64) *types.Header {
+	return &types.Header{Number: big.NewInt(number), Time: timestamp}
+}
+
+func TestPollIntervalFollowsBlockTime(t *testing.T) {
+	w := &Waiter{pollTimeDuration: 5 * time.Second, lock: &sync.Mutex{}}
+	if got := w.PollInterval(); got != 5*time.Second {
+		t.Errorf("PollInterval() before any block = %v, want %v", got, 5*time.Second)
+	}
+
+	w.observeHeader(header(100, 1000))
+	w.observeHeader(header(105, 1010))
+	if got := w.PollInterval(); got != 2*time.Second {
+		t.Errorf("PollInterval() after 5 blocks in 10s = %v, want %v", got, 2*time.Second)
+	}
+
+	// Stale or repeated heads must not change the estimate
+	w.observeHeader(header(105, 1010))
+	w.observeHeader(header(90, 900))
+	if got := w.PollInterval(); got != 2*time.Second {
+		t.Errorf("PollInterval() after stale heads = %v, want %v", got, 2*time.Second)
+	}
+	if w.lastBlockId != 105 {
+		t.Errorf("lastBlockId = %d, want 105", w.lastBlockId)
+	}
+}
+
+func TestPollIntervalIsClamped(t *testing.T) {
+	w := &Waiter{pollTimeDuration: time.Second, lock: &sync.Mutex{}}
+	w.observeHeader(header(1, 1000))
+	w.observeHeader(header(11, 1000))
+	if got := w.PollInterval(); got != minPollInterval {
+		t.Errorf("PollInterval() for sub-second blocks = %v, want %v", got, minPollInterval)
+	}
+
+	w = &Waiter{pollTimeDuration: time.Second, lock: &sync.Mutex{}}
+	w.observeHeader(header(1, 1000))
+	w.observeHeader(header(2, 1060))
+	if got := w.PollInterval(); got != maxPollInterval {
+		t.Errorf("PollInterval() for slow blocks = %v, want %v", got, maxPollInterval)
+	}
+}
+
+var logAddress = common.HexToAddress("0x2297aEbD383787A160DD0d9F71508148769342E3")
+
+// fakeChain serves the head, the receipts, the logs and new head subscriptions of a chain. Like a node, it only
+// returns the logs of the blocks up to its head.
+type fakeChain struct {
+	lock          sync.Mutex
+	head          uint64
+	receipts      map[common.Hash]*types.Receipt
+	failing       common.Hash // Transaction whose receipt request fails
+	logs          []types.Log
+	ranges        [][2]uint64 // Block ranges of the eth_getLogs requests
+	subscriptions int
+}
+
+type fakeFilter struct {
+	FromBlock hexutil.Uint64 `json:"fromBlock"`
+	ToBlock   hexutil.Uint64 `json:"toBlock"`
+}
+
+func (f *fakeChain) GetBlockByNumber(number string, full bool) *types.Header {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return &types.Header{Number: new(big.Int).SetUint64(f.head), Time: f.head * 2, Difficulty: big.NewInt(0)}
+}
+
+func (f *fakeChain) GetTransactionReceipt(hash common.Hash) (*types.Receipt, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if hash == f.failing {
+		return nil, errors.New("receipt unavailable")
+	}
+	return f.receipts[hash], nil
+}
+
+func (f *fakeChain) GetLogs(filter fakeFilter) []types.Log {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.ranges = append(f.ranges, [2]uint64{uint64(filter.FromBlock), uint64(filter.ToBlock)})
+	logs := make([]types.Log, 0)
+	for _, l := range f.logs {
+		if l.BlockNumber >= uint64(filter.FromBlock) && l.BlockNumber <= uint64(filter.ToBlock) && l.BlockNumber <= f.head {
+			logs = append(logs, l)
+		}
+	}
+	return logs
+}
+
+func (f *fakeChain) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	f.lock.Lock()
+	f.subscriptions++
+	f.lock.Unlock()
+	return notifier.CreateSubscription(), nil
+}
+
+func (f *fakeChain) addLog(blockNumber uint64, data byte) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.logs = append(f.logs, types.Log{
+		Address:     logAddress,
+		Topics:      []common.Hash{},
+		Data:        []byte{data},
+		BlockNumber: blockNumber,
+		TxHash:      common.BigToHash(new(big.Int).SetUint64(blockNumber)),
+	})
+}
+
+func (f *fakeChain) setHead(head uint64) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.head = head
+}
+
+func (f *fakeChain) requestedRanges() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.ranges)
+}
+
+func (f *fakeChain) subscriptionCount() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.subscriptions
+}
+
+// connTracker records the accepted connections so a test can drop them.
+type connTracker struct {
+	net.Listener
+	lock  sync.Mutex
+	conns []net.Conn
+}
+
+func (l *connTracker) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.lock.Lock()
+		l.conns = append(l.conns, conn)
+		l.lock.Unlock()
+	}
+	return conn, err
+}
+
+func (l *connTracker) dropAll() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+}
+
+func newFakeChainServer(t *testing.T, f *fakeChain, websocket bool) (string, *connTracker) {
+	if !websocket {
+		return clienttest.Serve(t, f), nil
+	}
+	httpServer := httptest.NewUnstartedServer(clienttest.NewServer(t, f).WebsocketHandler(nil))
+	tracker := &connTracker{Listener: httpServer.Listener}
+	httpServer.Listener = tracker
+	httpServer.Start()
+	t.Cleanup(httpServer.Close)
+	return "ws://" + strings.TrimPrefix(httpServer.URL, "http://"), tracker
+}
+
+// batchCounter records the methods of every JSON-RPC request it forwards, a single call counts as a batch of one.
+type batchCounter struct {
+	handler http.Handler
+	lock    sync.Mutex
+	batches [][]string
+}
+
+type batchCall struct {
+	Method string `json:"method"`
+}
+
+func (c *batchCounter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var calls []batchCall
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(body, &calls)
+	} else {
+		calls = make([]batchCall, 1)
+		err = json.Unmarshal(body, &calls[0])
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	methods := make([]string, 0, len(calls))
+	for _, call := range calls {
+		methods = append(methods, call.Method)
+	}
+	c.lock.Lock()
+	c.batches = append(c.batches, methods)
+	c.lock.Unlock()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	c.handler.ServeHTTP(w, r)
+}
+
+// takeBatches returns the batches recorded since the previous call.
+func (c *batchCounter) takeBatches() [][]string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	batches := c.batches
+	c.batches = nil
+	return batches
+}
+
+func receiveLog(t *testing.T, logs <-chan types.Log, timeout time.Duration) (types.Log, bool) {
+	select {
+	case l := <-logs:
+		return l, true
+	case <-time.After(timeout):
+		return types.Log{}, false
+	}
+}
+
+func TestWaitForLogScansFromStartBlockInChunks(t *testing.T) {
+	f := &fakeChain{head: 5000}
+	f.addLog(4500, 1)
+	url, _ := newFakeChainServer(t, f, false)
+	w := NewWaiter(clienttest.Dial(t, url), time.Second, false)
+
+	logs, err := w.WaitForLog(ethereum.FilterQuery{FromBlock: big.NewInt(0), Addresses: []common.Address{logAddress}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first poll learns the head, the next ones backfill from block 0 in ranges of at most maxLogBlockRange
+	for i := 0; i < 4; i++ {
+		w.poll()
+	}
+	l, ok := receiveLog(t, logs, time.Second)
+	if !ok {
+		t.Fatalf("no log after backfilling, requested ranges %v", f.ranges)
+	}
+	if l.BlockNumber != 4500 {
+		t.Errorf("log of block %d, want 4500", l.BlockNumber)
+	}
+
+	var next uint64
+	for _, r := range f.ranges {
+		if r[0] != next {
+			t.Errorf("range %v does not start at %d, blocks were skipped or scanned twice", r, next)
+		}
+		if r[1]-r[0]+1 > maxLogBlockRange {
+			t.Errorf("range %v is larger than %d blocks", r, maxLogBlockRange)
+		}
+		next = r[1] + 1
+	}
+	if len(f.ranges) != 3 {
+		t.Errorf("requested ranges %v, want 3 ranges up to block 5000", f.ranges)
+	}
+}
+
+func TestWaitForLogSkipsLogsRejectedByPredicate(t *testing.T) {
+	f := &fakeChain{head: 100}
+	f.addLog(10, 1)
+	f.addLog(20, 2)
+	url, _ := newFakeChainServer(t, f, false)
+	w := NewWaiter(clienttest.Dial(t, url), time.Second, false)
+
+	logs, err := w.WaitForLog(ethereum.FilterQuery{FromBlock: big.NewInt(0)}, func(l types.Log) bool {
+		return l.Data[0] == 2
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.poll()
+	w.poll()
+	l, ok := receiveLog(t, logs, time.Second)
+	if !ok {
+		t.Fatal("no log matching the predicate")
+	}
+	if l.BlockNumber != 20 {
+		t.Errorf("log of block %d, want the one of block 20 accepted by the predicate", l.BlockNumber)
+	}
+}
+
+func TestWaitForLogRescansBlocksBeyondALaggingEndpoint(t *testing.T) {
+	ahead := &fakeChain{head: 100}
+	ahead.addLog(95, 1)
+	lagging := &fakeChain{head: 90}
+	lagging.addLog(95, 1)
+	aheadUrl, _ := newFakeChainServer(t, ahead, false)
+	laggingUrl, _ := newFakeChainServer(t, lagging, false)
+	// Reads go to the endpoints in turn, so consecutive batches are answered by both
+	w := NewWaiter(clienttest.Dial(t, aheadUrl, laggingUrl), time.Second, false)
+
+	logs, err := w.WaitForLog(ethereum.FilterQuery{FromBlock: big.NewInt(50), Addresses: []common.Address{logAddress}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		w.poll()
+	}
+	l, ok := receiveLog(t, logs, time.Second)
+	if !ok {
+		t.Fatalf("no log, requested ranges %v ahead and %v lagging", ahead.ranges, lagging.ranges)
+	}
+	if l.BlockNumber != 95 {
+		t.Errorf("log of block %d, want 95", l.BlockNumber)
+	}
+}
+
+func TestWaitForLogCatchesUpAfterResubscribing(t *testing.T) {
+	delay := resubscribeDelay
+	resubscribeDelay = 50 * time.Millisecond
+	t.Cleanup(func() { resubscribeDelay = delay })
+
+	f := &fakeChain{head: 100}
+	url, tracker := newFakeChainServer(t, f, true)
+	w := NewWaiter(clienttest.Dial(t, url), time.Second, true)
+	stop := w.Start(context.Background())
+	defer stop()
+
+	logs, err := w.WaitForLog(ethereum.FilterQuery{Addresses: []common.Address{logAddress}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the catch-up scan of the first subscription
+	deadline := time.Now().Add(2 * time.Second)
+	for f.requestedRanges() < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The log is emitted in a block mined while the subscription is down, no new head announces it
+	tracker.dropAll()
+	f.addLog(101, 1)
+	f.setHead(101)
+
+	l, ok := receiveLog(t, logs, 3*time.Second)
+	if !ok {
+		t.Fatalf("no log after the subscription dropped, %d subscriptions", f.subscriptionCount())
+	}
+	if l.BlockNumber != 101 {
+		t.Errorf("log of block %d, want 101", l.BlockNumber)
+	}
+	if got := f.subscriptionCount(); got < 2 {
+		t.Errorf("%d subscriptions, want a new one after the drop", got)
+	}
+}
+
+func TestPollSendsOneBatch(t *testing.T) {
+	mined, pending, failing := common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x03")
+	f := &fakeChain{
+		head:     100,
+		receipts: map[common.Hash]*types.Receipt{mined: {Status: types.ReceiptStatusSuccessful, TxHash: mined, BlockNumber: big.NewInt(99), Logs: []*types.Log{}}},
+		failing:  failing,
+	}
+	f.addLog(50, 1)
+	counter := &batchCounter{handler: clienttest.NewServer(t, f)}
+	httpServer := httptest.NewServer(counter)
+	t.Cleanup(httpServer.Close)
+	w := NewWaiter(clienttest.Dial(t, httpServer.URL), time.Second, false)
+
+	minedReceipt, _ := w.WaitForTransaction(mined)
+	pendingReceipt, _ := w.WaitForTransaction(pending)
+	failingReceipt, _ := w.WaitForTransaction(failing)
+	logs, err := w.WaitForLog(ethereum.FilterQuery{FromBlock: big.NewInt(0), Addresses: []common.Address{logAddress}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receipt := "eth_getTransactionReceipt"
+	tests := []struct {
+		name string
+		want []string
+	}{
+		// The head is unknown before the first batch, so the logs are only requested from the second one
+		{"head and receipts", []string{"eth_getBlockByNumber", receipt, receipt, receipt}},
+		{"unresolved receipts and logs", []string{"eth_getBlockByNumber", receipt, receipt, "eth_getLogs"}},
+		{"unresolved receipts", []string{"eth_getBlockByNumber", receipt, receipt}},
+	}
+	for _, tt := range tests {
+		w.poll()
+		if got := counter.takeBatches(); len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+			t.Errorf("%s: poll sent %v, want a single batch %v", tt.name, got, tt.want)
+		}
+	}
+
+	select {
+	case r := <-minedReceipt:
+		if r.TxHash != mined {
+			t.Errorf("receipt of %s, want %s", r.TxHash.Hex(), mined.Hex())
+		}
+	default:
+		t.Error("no receipt for the mined transaction")
+	}
+	if _, ok := receiveLog(t, logs, time.Second); !ok {
+		t.Error("no log for the log waiter")
+	}
+	select {
+	case <-pendingReceipt:
+		t.Error("receipt for the pending transaction")
+	case <-failingReceipt:
+		t.Error("receipt for the transaction whose receipt request fails")
+	default:
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if len(w.transactionWaiters) != 2 || len(w.logWaiters) != 0 {
+		t.Errorf("%d transaction and %d log waiters left, want the 2 unresolved transactions", len(w.transactionWaiters), len(w.logWaiters))
+	}
+}